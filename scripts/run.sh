@@ -14,6 +14,8 @@ go get github.com/go-gl/mathgl/mgl32
 go get github.com/golang/freetype
 go get github.com/golang/freetype/truetype
 go get github.com/golang/glog
+go get golang.org/x/image/bmp
 go get golang.org/x/image/math/fixed
+go get golang.org/x/image/tiff
 
 go run $GITROOT/src/uniform-texture-coords/*.go -logtostderr=true $@
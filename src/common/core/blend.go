@@ -0,0 +1,130 @@
+// Copyright 2016 Arne Roomann-Kurrik
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"github.com/go-gl/gl/v3.3-core/gl"
+)
+
+type BlendMode int
+
+const (
+	BlendDefault BlendMode = iota // Defer to whatever mode the caller has set.
+	BlendAlpha
+	BlendPremultiplied
+	BlendAdditive
+	BlendMultiply
+	BlendScreen
+	BlendOpaque
+)
+
+// NeedsPremultiplied reports whether the mode only blends correctly when
+// fragment color is premultiplied by alpha.  Multiply and Screen have no
+// straight alpha form, so renderers premultiply their output when one of
+// these modes is active.
+func (m BlendMode) NeedsPremultiplied() bool {
+	switch m {
+	case BlendPremultiplied, BlendMultiply, BlendScreen:
+		return true
+	}
+	return false
+}
+
+// factors returns the blend function for the mode, given whether fragment
+// color is premultiplied by alpha.  Modes which need premultiplied color
+// use the same factors either way.
+func (m BlendMode) factors(premultiplied bool) (src, dst uint32) {
+	switch m {
+	case BlendOpaque:
+		return gl.ONE, gl.ZERO
+	case BlendPremultiplied:
+		return gl.ONE, gl.ONE_MINUS_SRC_ALPHA
+	case BlendAdditive:
+		if premultiplied {
+			return gl.ONE, gl.ONE
+		}
+		return gl.SRC_ALPHA, gl.ONE
+	case BlendMultiply:
+		return gl.DST_COLOR, gl.ONE_MINUS_SRC_ALPHA
+	case BlendScreen:
+		return gl.ONE, gl.ONE_MINUS_SRC_COLOR
+	}
+	if premultiplied {
+		return gl.ONE, gl.ONE_MINUS_SRC_ALPHA
+	}
+	return gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA
+}
+
+func (m BlendMode) apply(premultiplied bool) {
+	if m == BlendOpaque {
+		gl.Disable(gl.BLEND)
+		return
+	}
+	gl.Enable(gl.BLEND)
+	gl.BlendEquation(gl.FUNC_ADD)
+	gl.BlendFunc(m.factors(premultiplied))
+}
+
+// BlendState tracks the blend mode last sent to GL so that repeated
+// requests for the same mode don't issue redundant state changes.
+type BlendState struct {
+	mode          BlendMode
+	premultiplied bool
+	applied       bool
+}
+
+func NewBlendState() *BlendState {
+	return &BlendState{}
+}
+
+// Apply sets mode for fragments with straight (non-premultiplied) color.
+func (s *BlendState) Apply(mode BlendMode) {
+	s.ApplyPremultiplied(mode, false)
+}
+
+// ApplyPremultiplied sets mode for fragments whose color is premultiplied
+// by alpha when premultiplied is true, using the premultiplied form of the
+// mode's blend function.
+func (s *BlendState) ApplyPremultiplied(mode BlendMode, premultiplied bool) {
+	if mode == BlendDefault {
+		mode = BlendAlpha
+	}
+	if mode.NeedsPremultiplied() {
+		premultiplied = true
+	}
+	if s.applied && s.mode == mode && s.premultiplied == premultiplied {
+		return
+	}
+	mode.apply(premultiplied)
+	s.mode = mode
+	s.premultiplied = premultiplied
+	s.applied = true
+}
+
+func (s *BlendState) Mode() BlendMode {
+	return s.mode
+}
+
+// Premultiplied reports whether the current mode was applied for
+// premultiplied color.
+func (s *BlendState) Premultiplied() bool {
+	return s.premultiplied
+}
+
+// Invalidate forces the next Apply to hit GL.  Call this after anything
+// outside of the tracker touches blend state.
+func (s *BlendState) Invalidate() {
+	s.applied = false
+}
@@ -0,0 +1,66 @@
+// Copyright 2016 Arne Roomann-Kurrik
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"testing"
+)
+
+func TestBlendModeNeedsPremultiplied(t *testing.T) {
+	for _, c := range []struct {
+		mode BlendMode
+		want bool
+	}{
+		{BlendDefault, false},
+		{BlendAlpha, false},
+		{BlendPremultiplied, true},
+		{BlendAdditive, false},
+		{BlendMultiply, true},
+		{BlendScreen, true},
+		{BlendOpaque, false},
+	} {
+		if got := c.mode.NeedsPremultiplied(); got != c.want {
+			t.Errorf("Mode %v: got %v, want %v", c.mode, got, c.want)
+		}
+	}
+}
+
+func TestBlendModeFactors(t *testing.T) {
+	for _, c := range []struct {
+		mode          BlendMode
+		premultiplied bool
+		src, dst      uint32
+	}{
+		{BlendDefault, false, gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA},
+		{BlendDefault, true, gl.ONE, gl.ONE_MINUS_SRC_ALPHA},
+		{BlendAlpha, false, gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA},
+		{BlendAlpha, true, gl.ONE, gl.ONE_MINUS_SRC_ALPHA},
+		{BlendPremultiplied, false, gl.ONE, gl.ONE_MINUS_SRC_ALPHA},
+		{BlendPremultiplied, true, gl.ONE, gl.ONE_MINUS_SRC_ALPHA},
+		{BlendAdditive, false, gl.SRC_ALPHA, gl.ONE},
+		{BlendAdditive, true, gl.ONE, gl.ONE},
+		{BlendMultiply, false, gl.DST_COLOR, gl.ONE_MINUS_SRC_ALPHA},
+		{BlendMultiply, true, gl.DST_COLOR, gl.ONE_MINUS_SRC_ALPHA},
+		{BlendScreen, false, gl.ONE, gl.ONE_MINUS_SRC_COLOR},
+		{BlendScreen, true, gl.ONE, gl.ONE_MINUS_SRC_COLOR},
+		{BlendOpaque, false, gl.ONE, gl.ZERO},
+		{BlendOpaque, true, gl.ONE, gl.ZERO},
+	} {
+		if src, dst := c.mode.factors(c.premultiplied); src != c.src || dst != c.dst {
+			t.Errorf("Mode %v, premultiplied %v: got %X, %X, want %X, %X", c.mode, c.premultiplied, src, dst, c.src, c.dst)
+		}
+	}
+}
@@ -19,6 +19,8 @@ import (
 )
 
 type Buffer interface {
+	Bind()
+	BufferID() uint32
 }
 
 type GLBuffer struct {
@@ -58,6 +60,25 @@ func (b *GLBuffer) Upload(data interface{}, size int) {
 	}
 }
 
+// Reserve grows the buffer to at least size bytes.  Existing contents are
+// discarded when the buffer is reallocated.
+func (b *GLBuffer) Reserve(size int) (reallocated bool) {
+	b.Bind()
+	if size > b.bufferBytes {
+		b.bufferBytes = size
+		gl.BufferData(b.target, size, nil, gl.STREAM_DRAW)
+		reallocated = true
+	}
+	return
+}
+
+// UploadRange writes size bytes from data at offset bytes into the buffer,
+// which must already be large enough.
+func (b *GLBuffer) UploadRange(data interface{}, offset, size int) {
+	b.Bind()
+	gl.BufferSubData(b.target, offset, size, gl.Ptr(data))
+}
+
 func (b *GLBuffer) Size() int {
 	return b.bufferBytes
 }
@@ -83,3 +104,14 @@ func NewArrayBuffer() (b *ArrayBuffer) {
 	}
 	return
 }
+
+type ElementArrayBuffer struct {
+	*GLBuffer
+}
+
+func NewElementArrayBuffer() (b *ElementArrayBuffer) {
+	b = &ElementArrayBuffer{
+		GLBuffer: NewGLBuffer(gl.ELEMENT_ARRAY_BUFFER),
+	}
+	return
+}
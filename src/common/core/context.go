@@ -19,6 +19,7 @@ import (
 	"github.com/go-gl/gl/v3.3-core/gl"
 	"github.com/go-gl/glfw/v3.1/glfw"
 	"github.com/go-gl/mathgl/mgl32"
+	"image"
 )
 
 type Context struct {
@@ -32,6 +33,8 @@ type Context struct {
 	name          string
 	initialized   bool
 	Events        *Events
+	Blend         *BlendState
+	beforeSwap    []func()
 }
 
 func NewContext() (context *Context, err error) {
@@ -47,6 +50,7 @@ func NewContext() (context *Context, err error) {
 	context = &Context{
 		cursor:     true,
 		fullscreen: false,
+		Blend:      NewBlendState(),
 	}
 	return
 }
@@ -107,6 +111,7 @@ func (c *Context) createWindow() (err error) {
 	}
 	c.Events = newEvents(c.window)
 	c.window.MakeContextCurrent()
+	c.Blend.Invalidate()
 	return
 }
 
@@ -125,8 +130,7 @@ func (c *Context) CreateWindow(w, h int, name string) (err error) {
 	}
 	c.OpenGLVersion = glfw.GetVersionString()
 	c.ShaderVersion = gl.GoStr(gl.GetString(gl.SHADING_LANGUAGE_VERSION))
-	gl.Enable(gl.BLEND)
-	gl.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
+	c.Blend.Apply(BlendAlpha)
 	gl.ClearColor(0.0, 0.0, 0.0, 1.0)
 	gl.Disable(gl.CULL_FACE)
 	glfw.SwapInterval(1)
@@ -141,7 +145,40 @@ func (c *Context) Clear() {
 	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
 }
 
+// FramebufferSize is the size of the window in pixels, which can differ
+// from the size requested on high density displays.
+func (c *Context) FramebufferSize() (w, h int) {
+	return c.window.GetFramebufferSize()
+}
+
+// ReadPixels copies the back buffer into an image with the top row first.
+// Call it after rendering and before SwapBuffers, after which the back
+// buffer contents are undefined.
+func (c *Context) ReadPixels() (img *image.RGBA, err error) {
+	var w, h = c.FramebufferSize()
+	if w == 0 || h == 0 {
+		err = fmt.Errorf("Framebuffer has no pixels")
+		return
+	}
+	img = image.NewRGBA(image.Rect(0, 0, w, h))
+	gl.ReadBuffer(gl.BACK)
+	readPixels(img, 0, 0)
+	if e := gl.GetError(); e != 0 {
+		err = fmt.Errorf("OpenGL error reading pixels: %X", e)
+	}
+	return
+}
+
+// BeforeSwap registers f to run at the start of every SwapBuffers call,
+// while the finished frame is still in the back buffer.
+func (c *Context) BeforeSwap(f func()) {
+	c.beforeSwap = append(c.beforeSwap, f)
+}
+
 func (c *Context) SwapBuffers() {
+	for _, f := range c.beforeSwap {
+		f()
+	}
 	c.window.SwapBuffers()
 }
 
@@ -18,16 +18,47 @@ import (
 	"github.com/go-gl/glfw/v3.1/glfw"
 )
 
+type Key int
+
+const (
+	KeyF12 = Key(glfw.KeyF12)
+)
+
 type Events struct {
-	window *glfw.Window
+	window  *glfw.Window
+	pressed map[Key]bool
 }
 
 func newEvents(window *glfw.Window) (e *Events) {
-	return &Events{
-		window: window,
+	e = &Events{
+		window:  window,
+		pressed: map[Key]bool{},
+	}
+	window.SetKeyCallback(e.onKey)
+	return
+}
+
+func (e *Events) onKey(w *glfw.Window, key glfw.Key, scancode int, action glfw.Action, mods glfw.ModifierKey) {
+	if action == glfw.Press {
+		e.pressed[Key(key)] = true
 	}
 }
 
-func (e Events) Poll() {
+// Poll processes pending window events.
+func (e *Events) Poll() {
 	glfw.PollEvents()
 }
+
+// KeyPressed reports whether key has been pressed since KeyPressed last
+// returned true for it.  Presses are held until asked for, so they are not
+// lost on frames which skip the code that checks for them.
+func (e *Events) KeyPressed(key Key) (pressed bool) {
+	if pressed = e.pressed[key]; pressed {
+		delete(e.pressed, key)
+	}
+	return
+}
+
+func (e *Events) KeyDown(key Key) bool {
+	return e.window.GetKey(glfw.Key(key)) == glfw.Press
+}
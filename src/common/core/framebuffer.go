@@ -0,0 +1,178 @@
+// Copyright 2016 Arne Roomann-Kurrik
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"fmt"
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+type ColorFormat int
+
+const (
+	ColorRGBA8   ColorFormat = iota // 8 bits per channel.
+	ColorRGBA16F                    // Half float per channel, for HDR.
+)
+
+func (f ColorFormat) formats() (internal int32, xtype uint32) {
+	if f == ColorRGBA16F {
+		return gl.RGBA16F, gl.HALF_FLOAT
+	}
+	return gl.RGBA8, gl.UNSIGNED_BYTE
+}
+
+type FramebufferOptions struct {
+	Colors  []ColorFormat // One texture per entry; defaults to a single ColorRGBA8.
+	Depth   bool
+	Stencil bool
+	Texture TextureOptions // Filtering and wrapping for the color textures.
+}
+
+// Framebuffer renders into textures instead of the window.  Bind directs
+// drawing into it and sets the viewport to cover it; Unbind restores the
+// framebuffer and viewport that were active before.
+type Framebuffer struct {
+	id       uint32
+	Width    int
+	Height   int
+	opts     FramebufferOptions
+	textures []*Texture
+	depth    uint32
+	previous int32
+	viewport [4]int32
+}
+
+func NewFramebuffer(w, h int, opts FramebufferOptions) (f *Framebuffer, err error) {
+	if len(opts.Colors) == 0 {
+		opts.Colors = []ColorFormat{ColorRGBA8}
+	}
+	f = &Framebuffer{
+		opts:     opts,
+		textures: make([]*Texture, len(opts.Colors)),
+	}
+	gl.GenFramebuffers(1, &f.id)
+	for i := range f.textures {
+		f.textures[i] = &Texture{
+			options:  opts.Texture,
+			bottomUp: true,
+		}
+		gl.GenTextures(1, &f.textures[i].id)
+	}
+	if opts.Depth || opts.Stencil {
+		gl.GenRenderbuffers(1, &f.depth)
+	}
+	if err = f.Resize(w, h); err != nil {
+		f.Delete()
+		f = nil
+	}
+	return
+}
+
+// Resize reallocates every attachment.  Contents are lost; the Texture
+// values stay the same but their Size changes.
+func (f *Framebuffer) Resize(w, h int) (err error) {
+	var (
+		previous    int32
+		attachments = make([]uint32, len(f.textures))
+	)
+	f.Width = w
+	f.Height = h
+	gl.GetIntegerv(gl.FRAMEBUFFER_BINDING, &previous)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, f.id)
+	for i, texture := range f.textures {
+		var internal, xtype = f.opts.Colors[i].formats()
+		gl.BindTexture(gl.TEXTURE_2D, texture.id)
+		texture.options.apply()
+		gl.TexImage2D(gl.TEXTURE_2D, 0, internal, int32(w), int32(h), 0, gl.RGBA, xtype, nil)
+		if texture.options.Mipmaps {
+			gl.GenerateMipmap(gl.TEXTURE_2D)
+		}
+		texture.Size = mgl32.Vec2{float32(w), float32(h)}
+		texture.OriginalSize = texture.Size
+		attachments[i] = gl.COLOR_ATTACHMENT0 + uint32(i)
+		gl.FramebufferTexture2D(gl.FRAMEBUFFER, attachments[i], gl.TEXTURE_2D, texture.id, 0)
+	}
+	gl.BindTexture(gl.TEXTURE_2D, 0)
+	gl.DrawBuffers(int32(len(attachments)), &attachments[0])
+	if f.depth != 0 {
+		var (
+			format     uint32 = gl.DEPTH24_STENCIL8
+			attachment uint32 = gl.DEPTH_STENCIL_ATTACHMENT
+		)
+		if !f.opts.Stencil {
+			format, attachment = gl.DEPTH_COMPONENT24, gl.DEPTH_ATTACHMENT
+		} else if !f.opts.Depth {
+			format, attachment = gl.STENCIL_INDEX8, gl.STENCIL_ATTACHMENT
+		}
+		gl.BindRenderbuffer(gl.RENDERBUFFER, f.depth)
+		gl.RenderbufferStorage(gl.RENDERBUFFER, format, int32(w), int32(h))
+		gl.BindRenderbuffer(gl.RENDERBUFFER, 0)
+		gl.FramebufferRenderbuffer(gl.FRAMEBUFFER, attachment, gl.RENDERBUFFER, f.depth)
+	}
+	if status := gl.CheckFramebufferStatus(gl.FRAMEBUFFER); status != gl.FRAMEBUFFER_COMPLETE {
+		err = fmt.Errorf("Framebuffer incomplete: %X", status)
+	}
+	gl.BindFramebuffer(gl.FRAMEBUFFER, uint32(previous))
+	return
+}
+
+// Texture returns the first color attachment.  It is drawn with the bottom
+// row first and can be given to a sprites.Sheet like any other texture.
+// The framebuffer owns it; deleting it elsewhere breaks the framebuffer.
+func (f *Framebuffer) Texture() *Texture {
+	return f.textures[0]
+}
+
+func (f *Framebuffer) Textures() []*Texture {
+	return f.textures
+}
+
+func (f *Framebuffer) Bind() {
+	gl.GetIntegerv(gl.FRAMEBUFFER_BINDING, &f.previous)
+	gl.GetIntegerv(gl.VIEWPORT, &f.viewport[0])
+	gl.BindFramebuffer(gl.FRAMEBUFFER, f.id)
+	gl.Viewport(0, 0, int32(f.Width), int32(f.Height))
+}
+
+func (f *Framebuffer) Unbind() {
+	gl.BindFramebuffer(gl.FRAMEBUFFER, uint32(f.previous))
+	gl.Viewport(f.viewport[0], f.viewport[1], f.viewport[2], f.viewport[3])
+}
+
+func (f *Framebuffer) Clear() {
+	var mask uint32 = gl.COLOR_BUFFER_BIT
+	if f.opts.Depth {
+		mask |= gl.DEPTH_BUFFER_BIT
+	}
+	if f.opts.Stencil {
+		mask |= gl.STENCIL_BUFFER_BIT
+	}
+	gl.Clear(mask)
+}
+
+func (f *Framebuffer) Delete() {
+	for _, texture := range f.textures {
+		texture.Delete()
+	}
+	if f.depth != 0 {
+		gl.DeleteRenderbuffers(1, &f.depth)
+		f.depth = 0
+	}
+	if f.id != 0 {
+		gl.DeleteFramebuffers(1, &f.id)
+		f.id = 0
+	}
+}
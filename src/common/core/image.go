@@ -16,13 +16,68 @@ package core
 
 import (
 	"bufio"
-	"bytes"
+	"fmt"
+	"github.com/go-gl/gl/v3.3-core/gl"
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/tiff"
 	"image"
+	"image/color"
 	"image/draw"
+	_ "image/gif"
+	_ "image/jpeg"
 	"image/png"
 	"os"
 )
 
+// LoadImage decodes any registered format, which includes PNG, JPEG, GIF,
+// BMP and TIFF.  The format is detected from the file contents rather than
+// the extension.
+func LoadImage(path string) (img image.Image, err error) {
+	var file *os.File
+	if file, err = os.Open(path); err != nil {
+		return
+	}
+	defer file.Close()
+	if img, _, err = image.Decode(file); err != nil {
+		err = fmt.Errorf("Could not decode %v: %v", path, err)
+	}
+	return
+}
+
+// ApplyAlphaMask combines a color image without alpha, such as a JPEG,
+// with a mask whose brightness (or alpha, if it has any) supplies the
+// opacity.  The two images must be the same size.
+func ApplyAlphaMask(img, mask image.Image) (out *image.NRGBA, err error) {
+	var (
+		bounds     = img.Bounds()
+		maskBounds = mask.Bounds()
+	)
+	if bounds.Dx() != maskBounds.Dx() || bounds.Dy() != maskBounds.Dy() {
+		err = fmt.Errorf("Alpha mask is %v but image is %v", maskBounds.Size(), bounds.Size())
+		return
+	}
+	out = image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(out, out.Bounds(), img, bounds.Min, draw.Src)
+	var useAlpha bool
+	if opaque, ok := mask.(interface {
+		Opaque() bool
+	}); ok {
+		useAlpha = !opaque.Opaque()
+	}
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			var m = mask.At(maskBounds.Min.X+x, maskBounds.Min.Y+y)
+			if useAlpha {
+				_, _, _, a := m.RGBA()
+				out.Pix[out.PixOffset(x, y)+3] = uint8(a >> 8)
+			} else {
+				out.Pix[out.PixOffset(x, y)+3] = color.GrayModel.Convert(m).(color.Gray).Y
+			}
+		}
+	}
+	return
+}
+
 func LoadPNG(path string) (img image.Image, err error) {
 	var file *os.File
 	if file, err = os.Open(path); err != nil {
@@ -50,38 +105,110 @@ func WritePNG(path string, img image.Image) (err error) {
 	return
 }
 
-func imageBytes(img image.Image) (buf *bytes.Buffer, err error) {
+// texturePixels returns RGBA bytes for uploading with UNSIGNED_BYTE along
+// with the row length in pixels.  Rows are in image order, so the top of
+// the image lands at texture coordinate v=0.  Images whose alpha
+// representation already matches, *image.RGBA when premultiplied and
+// *image.NRGBA when not, are used as-is without copying; anything else is
+// converted.
+func texturePixels(img image.Image, premultiplied bool) (pix []byte, rowLength int) {
+	switch src := img.(type) {
+	case *image.RGBA:
+		if premultiplied {
+			return src.Pix[src.PixOffset(src.Rect.Min.X, src.Rect.Min.Y):], src.Stride / 4
+		}
+	case *image.NRGBA:
+		if !premultiplied {
+			return src.Pix[src.PixOffset(src.Rect.Min.X, src.Rect.Min.Y):], src.Stride / 4
+		}
+	}
 	var (
-		bounds image.Rectangle = img.Bounds()
-		rgba   *image.RGBA
-		data   []byte
+		bounds = img.Bounds()
+		rect   = image.Rect(0, 0, bounds.Dx(), bounds.Dy())
+		dst    draw.Image
 	)
-	buf = &bytes.Buffer{}
-	rgba = image.NewRGBA(bounds)
-	draw.Draw(rgba, bounds, img, bounds.Min, draw.Src)
-	data = make([]byte, len(rgba.Pix))
+	if premultiplied {
+		rgba := image.NewRGBA(rect)
+		pix, rowLength, dst = rgba.Pix, rgba.Stride/4, rgba
+	} else {
+		nrgba := image.NewNRGBA(rect)
+		pix, rowLength, dst = nrgba.Pix, nrgba.Stride/4, nrgba
+	}
+	draw.Draw(dst, rect, img, bounds.Min, draw.Src)
+	return
+}
+
+// flipRows reverses the row order of img in place, converting between GL's
+// bottom-first pixel order and image order.
+func flipRows(img *image.RGBA) {
 	var (
-		destOffset int = len(data) - rgba.Stride
+		h   = img.Rect.Dy()
+		n   = img.Rect.Dx() * 4
+		tmp = make([]byte, n)
 	)
-	for srcOffset := 0; srcOffset < len(rgba.Pix); {
+	for y := 0; y < h/2; y++ {
 		var (
-			dest   = data[destOffset : destOffset+rgba.Stride]
-			source = rgba.Pix[srcOffset : srcOffset+rgba.Stride]
+			top    = img.Pix[y*img.Stride : y*img.Stride+n]
+			bottom = img.Pix[(h-1-y)*img.Stride : (h-1-y)*img.Stride+n]
 		)
-		copy(dest, source)
-		destOffset -= rgba.Stride
-		srcOffset += rgba.Stride
+		copy(tmp, top)
+		copy(top, bottom)
+		copy(bottom, tmp)
 	}
-	for x := 0; x < len(data); {
-		buf.WriteByte(data[x+3])
-		buf.WriteByte(data[x+2])
-		buf.WriteByte(data[x+1])
-		buf.WriteByte(data[x+0])
-		x += 4
+}
+
+// flipPixels copies width by height pixels from pix, whose rows are
+// rowLength pixels apart, into a tightly packed buffer with the rows in
+// reverse order.
+func flipPixels(pix []byte, rowLength, width, height int) (out []byte) {
+	var n = width * 4
+	out = make([]byte, n*height)
+	for y := 0; y < height; y++ {
+		copy(out[(height-1-y)*n:(height-y)*n], pix[y*rowLength*4:y*rowLength*4+n])
 	}
 	return
 }
 
+// readPixels fills img from the bound read framebuffer, starting at x, y in
+// GL window coordinates.
+func readPixels(img *image.RGBA, x, y int) {
+	gl.PixelStorei(gl.PACK_ROW_LENGTH, int32(img.Stride/4))
+	gl.ReadPixels(
+		int32(x),
+		int32(y),
+		int32(img.Rect.Dx()),
+		int32(img.Rect.Dy()),
+		gl.RGBA,
+		gl.UNSIGNED_BYTE,
+		gl.Ptr(&img.Pix[0]),
+	)
+	gl.PixelStorei(gl.PACK_ROW_LENGTH, 0)
+	flipRows(img)
+}
+
+// Opaque sets every pixel's alpha to fully opaque, which is how the window
+// presents the framebuffer regardless of the alpha it holds.
+func Opaque(img *image.RGBA) {
+	for i := 3; i < len(img.Pix); i += 4 {
+		img.Pix[i] = 255
+	}
+}
+
+type subImager interface {
+	SubImage(r image.Rectangle) image.Image
+}
+
+// subImage returns the part of img within region, sharing pixels where
+// the image type allows it.
+func subImage(img image.Image, region image.Rectangle) image.Image {
+	if sub, ok := img.(subImager); ok {
+		return sub.SubImage(region)
+	}
+	var out = image.NewRGBA(region)
+	draw.Draw(out, region, img, region.Min, draw.Src)
+	return out
+}
+
 func pow2(i int) int {
 	p2 := 1
 	for p2 < i {
@@ -93,13 +220,13 @@ func pow2(i int) int {
 func getPow2Image(img image.Image) image.Image {
 	var (
 		bounds = img.Bounds()
-		width  = pow2(bounds.Max.X)
-		height = pow2(bounds.Max.Y)
+		width  = pow2(bounds.Dx())
+		height = pow2(bounds.Dy())
 	)
-	if width == bounds.Max.X && height == bounds.Max.Y {
+	if width == bounds.Dx() && height == bounds.Dy() {
 		return img
 	}
 	out := image.NewRGBA(image.Rect(0, 0, width, height))
-	draw.Draw(out, bounds, img, image.ZP, draw.Src)
+	draw.Draw(out, image.Rect(0, 0, bounds.Dx(), bounds.Dy()), img, bounds.Min, draw.Src)
 	return out
 }
@@ -0,0 +1,94 @@
+// Copyright 2016 Arne Roomann-Kurrik
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestPow2ImagePadsAtTopLeft(t *testing.T) {
+	var (
+		img    = image.NewNRGBA(image.Rect(10, 20, 106, 68))
+		red    = color.NRGBA{255, 0, 0, 255}
+		padded image.Image
+	)
+	img.Set(10, 20, red)
+	img.Set(105, 67, red)
+	padded = getPow2Image(img)
+	if got, want := padded.Bounds(), image.Rect(0, 0, 128, 64); got != want {
+		t.Fatalf("Got bounds %v, want %v", got, want)
+	}
+	for _, p := range []image.Point{{0, 0}, {95, 47}} {
+		if got := color.NRGBAModel.Convert(padded.At(p.X, p.Y)); got != red {
+			t.Errorf("Pixel %v is %v, want %v", p, got, red)
+		}
+	}
+	if _, _, _, a := padded.At(96, 0).RGBA(); a != 0 {
+		t.Errorf("Padding is not transparent")
+	}
+}
+
+func TestPow2ImageKeepsPow2Sizes(t *testing.T) {
+	var img = image.NewNRGBA(image.Rect(0, 0, 64, 32))
+	if getPow2Image(img) != image.Image(img) {
+		t.Errorf("Power of two image was copied")
+	}
+}
+
+func TestFlipPixels(t *testing.T) {
+	var (
+		img = image.NewRGBA(image.Rect(0, 0, 4, 3))
+		sub = img.SubImage(image.Rect(1, 0, 3, 3)).(*image.RGBA)
+	)
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 4; x++ {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), 0, 255})
+		}
+	}
+	var (
+		pix, rowLength = texturePixels(sub, true)
+		flipped        = flipPixels(pix, rowLength, 2, 3)
+	)
+	if len(flipped) != 2*3*4 {
+		t.Fatalf("Got %v bytes, want %v", len(flipped), 2*3*4)
+	}
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 2; x++ {
+			var i = (y*2 + x) * 4
+			if got, want := flipped[i:i+2], []byte{uint8(x + 1), uint8(2 - y)}; got[0] != want[0] || got[1] != want[1] {
+				t.Errorf("Pixel %v,%v is %v, want %v", x, y, got, want)
+			}
+		}
+	}
+}
+
+func TestPremultipliedSourcePixels(t *testing.T) {
+	var (
+		half  = color.NRGBA{64, 32, 16, 128}
+		opts  = TextureOptions{PremultipliedSource: true}
+		nrgba = image.NewNRGBA(image.Rect(0, 0, 1, 1))
+		pal   = image.NewPaletted(image.Rect(0, 0, 1, 1), color.Palette{half})
+		rgba  = image.NewRGBA(image.Rect(0, 0, 1, 1))
+	)
+	nrgba.SetNRGBA(0, 0, half)
+	rgba.Pix = []byte{64, 32, 16, 128}
+	for _, img := range []image.Image{nrgba, pal, rgba} {
+		if pix, _ := opts.pixels(img); pix[0] != 64 || pix[1] != 32 || pix[2] != 16 || pix[3] != 128 {
+			t.Errorf("%T uploaded as %v, want %v", img, pix[:4], []byte{64, 32, 16, 128})
+		}
+	}
+}
@@ -30,6 +30,26 @@ func (u *Uniform) Mat4(m mgl32.Mat4) {
 	gl.UniformMatrix4fv(u.location, 1, false, &m[0])
 }
 
+func (u *Uniform) Int(i int32) {
+	gl.Uniform1i(u.location, i)
+}
+
+func (u *Uniform) Float(f float32) {
+	gl.Uniform1f(u.location, f)
+}
+
+func (u *Uniform) Vec2(v mgl32.Vec2) {
+	gl.Uniform2f(u.location, v[0], v[1])
+}
+
+func (u *Uniform) Vec3(v mgl32.Vec3) {
+	gl.Uniform3f(u.location, v[0], v[1], v[2])
+}
+
+func (u *Uniform) Vec4(v mgl32.Vec4) {
+	gl.Uniform4f(u.location, v[0], v[1], v[2], v[3])
+}
+
 type Program struct {
 	vao     uint32
 	program uint32
@@ -0,0 +1,139 @@
+// Copyright 2016 Arne Roomann-Kurrik
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"image"
+)
+
+type ReadbackFrame struct {
+	Image *image.RGBA
+	Tag   int
+}
+
+type pendingRead struct {
+	id       uint32
+	capacity int
+	fence    uintptr
+	w        int
+	h        int
+	tag      int
+}
+
+// AsyncReader reads the framebuffer through a ring of pixel buffer objects.
+// Read only queues the copy on the GPU; the pixels are fetched by Collect a
+// frame or more later, once they are ready, so the CPU does not stall
+// waiting for rendering to finish.
+type AsyncReader struct {
+	slots []pendingRead
+	next  int
+	queue []int
+	done  []ReadbackFrame
+}
+
+func NewAsyncReader(count int) (r *AsyncReader) {
+	if count < 1 {
+		count = 1
+	}
+	var ids = make([]uint32, count)
+	gl.GenBuffers(int32(count), &ids[0])
+	r = &AsyncReader{
+		slots: make([]pendingRead, count),
+	}
+	for i, id := range ids {
+		r.slots[i].id = id
+	}
+	return
+}
+
+// Read queues a copy of the bottom left w by h pixels of the read
+// framebuffer.  Tag is returned with the frame to identify it.  If every
+// buffer is in use the oldest read is finished first.
+func (r *AsyncReader) Read(w, h, tag int) {
+	var (
+		index = r.next
+		slot  = &r.slots[index]
+		size  = w * h * 4
+	)
+	if slot.fence != 0 {
+		r.finish()
+	}
+	gl.BindBuffer(gl.PIXEL_PACK_BUFFER, slot.id)
+	if size > slot.capacity {
+		slot.capacity = size
+		gl.BufferData(gl.PIXEL_PACK_BUFFER, size, nil, gl.STREAM_READ)
+	}
+	gl.PixelStorei(gl.PACK_ROW_LENGTH, 0)
+	gl.ReadPixels(0, 0, int32(w), int32(h), gl.RGBA, gl.UNSIGNED_BYTE, nil)
+	gl.BindBuffer(gl.PIXEL_PACK_BUFFER, 0)
+	slot.fence = gl.FenceSync(gl.SYNC_GPU_COMMANDS_COMPLETE, 0)
+	slot.w = w
+	slot.h = h
+	slot.tag = tag
+	r.queue = append(r.queue, index)
+	r.next = (r.next + 1) % len(r.slots)
+}
+
+// Pending returns the number of reads not yet collected.
+func (r *AsyncReader) Pending() int {
+	return len(r.queue) + len(r.done)
+}
+
+// Collect returns completed reads in the order they were issued.  With
+// wait set it blocks until every queued read is complete.
+func (r *AsyncReader) Collect(wait bool) (frames []ReadbackFrame) {
+	for len(r.queue) > 0 {
+		if !wait {
+			var status = gl.ClientWaitSync(r.slots[r.queue[0]].fence, 0, 0)
+			if status != gl.ALREADY_SIGNALED && status != gl.CONDITION_SATISFIED {
+				break
+			}
+		}
+		r.finish()
+	}
+	frames = r.done
+	r.done = nil
+	return
+}
+
+// finish completes the oldest queued read.
+func (r *AsyncReader) finish() {
+	var slot = &r.slots[r.queue[0]]
+	r.queue = r.queue[1:]
+	gl.ClientWaitSync(slot.fence, gl.SYNC_FLUSH_COMMANDS_BIT, ringFenceTimeout)
+	gl.DeleteSync(slot.fence)
+	slot.fence = 0
+	var img = image.NewRGBA(image.Rect(0, 0, slot.w, slot.h))
+	if len(img.Pix) > 0 {
+		gl.BindBuffer(gl.PIXEL_PACK_BUFFER, slot.id)
+		gl.GetBufferSubData(gl.PIXEL_PACK_BUFFER, 0, len(img.Pix), gl.Ptr(&img.Pix[0]))
+		gl.BindBuffer(gl.PIXEL_PACK_BUFFER, 0)
+		flipRows(img)
+	}
+	r.done = append(r.done, ReadbackFrame{Image: img, Tag: slot.tag})
+}
+
+func (r *AsyncReader) Delete() {
+	for i := range r.slots {
+		if r.slots[i].fence != 0 {
+			gl.DeleteSync(r.slots[i].fence)
+			r.slots[i].fence = 0
+		}
+		gl.DeleteBuffers(1, &r.slots[i].id)
+	}
+	r.queue = nil
+	r.done = nil
+}
@@ -0,0 +1,217 @@
+// Copyright 2016 Arne Roomann-Kurrik
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"fmt"
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"unsafe"
+)
+
+type UploadStrategy int
+
+const (
+	UploadSubData       UploadStrategy = iota // BufferSubData into one buffer.
+	UploadOrphan                              // BufferData(nil) before each write.
+	UploadRoundRobin                          // Rotate through several buffers.
+	UploadMapInvalidate                       // MapBufferRange with INVALIDATE_BUFFER.
+	UploadRing                                // Unsynchronized ring buffer guarded by fences.
+)
+
+var uploadStrategyNames = map[string]UploadStrategy{
+	"subdata":    UploadSubData,
+	"orphan":     UploadOrphan,
+	"roundrobin": UploadRoundRobin,
+	"map":        UploadMapInvalidate,
+	"ring":       UploadRing,
+}
+
+func ParseUploadStrategy(name string) (strategy UploadStrategy, err error) {
+	var exists bool
+	if strategy, exists = uploadStrategyNames[name]; !exists {
+		err = fmt.Errorf("Unknown upload strategy %v", name)
+	}
+	return
+}
+
+const ringFenceTimeout = 1000000000 // Nanoseconds.
+
+// StreamingBuffer is written every draw.  Depending on the strategy the
+// data may land in a different buffer or at a different offset on each
+// Upload, so callers must consult BufferID and Offset before pointing
+// vertex attributes at it.
+type StreamingBuffer struct {
+	target   uint32
+	strategy UploadStrategy
+	ids      []uint32
+	sizes    []int
+	current  int
+	offset   int
+	segment  int
+	fences   []uintptr
+	pending  int
+}
+
+func NewStreamingBuffer(target uint32, strategy UploadStrategy, count int) (b *StreamingBuffer) {
+	if count < 1 || strategy == UploadSubData || strategy == UploadOrphan || strategy == UploadMapInvalidate {
+		count = 1
+	}
+	var buffers = count
+	if strategy == UploadRing {
+		// The ring is a single buffer split into count segments.
+		buffers = 1
+	}
+	b = &StreamingBuffer{
+		target:   target,
+		strategy: strategy,
+		ids:      make([]uint32, buffers),
+		sizes:    make([]int, buffers),
+		fences:   make([]uintptr, count),
+		pending:  -1,
+	}
+	gl.GenBuffers(int32(buffers), &b.ids[0])
+	b.Bind()
+	return
+}
+
+func NewStreamingArrayBuffer(strategy UploadStrategy, count int) *StreamingBuffer {
+	return NewStreamingBuffer(gl.ARRAY_BUFFER, strategy, count)
+}
+
+func (b *StreamingBuffer) Strategy() UploadStrategy {
+	return b.strategy
+}
+
+func (b *StreamingBuffer) BufferID() uint32 {
+	if b.strategy == UploadRing {
+		return b.ids[0]
+	}
+	return b.ids[b.current]
+}
+
+// Offset returns the byte offset of the most recent Upload within the
+// buffer returned by BufferID.
+func (b *StreamingBuffer) Offset() int {
+	return b.offset
+}
+
+func (b *StreamingBuffer) Size() int {
+	if b.strategy == UploadRing {
+		return b.segment
+	}
+	return b.sizes[b.current]
+}
+
+func (b *StreamingBuffer) Bind() {
+	gl.BindBuffer(b.target, b.BufferID())
+}
+
+func (b *StreamingBuffer) Delete() {
+	b.deleteFences()
+	gl.DeleteBuffers(int32(len(b.ids)), &b.ids[0])
+}
+
+func (b *StreamingBuffer) Upload(data interface{}, size int) {
+	switch b.strategy {
+	case UploadOrphan:
+		b.uploadOrphan(data, size)
+	case UploadRoundRobin:
+		b.current = (b.current + 1) % len(b.ids)
+		b.uploadSubData(data, size)
+	case UploadMapInvalidate:
+		b.uploadMapped(data, size)
+	case UploadRing:
+		b.uploadRing(data, size)
+	default:
+		b.uploadSubData(data, size)
+	}
+}
+
+func (b *StreamingBuffer) uploadSubData(data interface{}, size int) {
+	b.Bind()
+	if size > b.sizes[b.current] {
+		b.sizes[b.current] = size
+		gl.BufferData(b.target, size, gl.Ptr(data), gl.STREAM_DRAW)
+	} else {
+		gl.BufferSubData(b.target, 0, size, gl.Ptr(data))
+	}
+}
+
+func (b *StreamingBuffer) uploadOrphan(data interface{}, size int) {
+	b.Bind()
+	if size > b.sizes[b.current] {
+		b.sizes[b.current] = size
+	}
+	gl.BufferData(b.target, b.sizes[b.current], nil, gl.STREAM_DRAW)
+	gl.BufferSubData(b.target, 0, size, gl.Ptr(data))
+}
+
+func (b *StreamingBuffer) uploadMapped(data interface{}, size int) {
+	b.Bind()
+	if size > b.sizes[b.current] {
+		b.sizes[b.current] = size
+		gl.BufferData(b.target, size, nil, gl.STREAM_DRAW)
+	}
+	b.write(0, data, size, gl.MAP_WRITE_BIT|gl.MAP_INVALIDATE_BUFFER_BIT)
+}
+
+func (b *StreamingBuffer) uploadRing(data interface{}, size int) {
+	var segments = len(b.fences)
+	b.Bind()
+	if b.pending >= 0 {
+		// The draw reading the previous segment has been issued by now.
+		b.fences[b.pending] = gl.FenceSync(gl.SYNC_GPU_COMMANDS_COMPLETE, 0)
+	}
+	if size > b.segment {
+		b.deleteFences()
+		b.segment = size
+		b.current = 0
+		gl.BufferData(b.target, b.segment*segments, nil, gl.STREAM_DRAW)
+	} else {
+		b.current = (b.current + 1) % segments
+	}
+	if fence := b.fences[b.current]; fence != 0 {
+		gl.ClientWaitSync(fence, gl.SYNC_FLUSH_COMMANDS_BIT, ringFenceTimeout)
+		gl.DeleteSync(fence)
+		b.fences[b.current] = 0
+	}
+	b.offset = b.current * b.segment
+	b.write(b.offset, data, size, gl.MAP_WRITE_BIT|gl.MAP_UNSYNCHRONIZED_BIT|gl.MAP_INVALIDATE_RANGE_BIT)
+	b.pending = b.current
+}
+
+func (b *StreamingBuffer) write(offset int, data interface{}, size int, access uint32) {
+	var dest = gl.MapBufferRange(b.target, offset, size, access)
+	if dest == nil {
+		// Mapping failed; fall back to a plain copy.
+		gl.BufferSubData(b.target, offset, size, gl.Ptr(data))
+		return
+	}
+	copy(
+		unsafe.Slice((*byte)(dest), size),
+		unsafe.Slice((*byte)(gl.Ptr(data)), size),
+	)
+	gl.UnmapBuffer(b.target)
+}
+
+func (b *StreamingBuffer) deleteFences() {
+	for i, fence := range b.fences {
+		if fence != 0 {
+			gl.DeleteSync(fence)
+			b.fences[i] = 0
+		}
+	}
+	b.pending = -1
+}
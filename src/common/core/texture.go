@@ -15,10 +15,11 @@
 package core
 
 import (
-	"bytes"
+	"fmt"
 	"github.com/go-gl/gl/v3.3-core/gl"
 	"github.com/go-gl/mathgl/mgl32"
 	"image"
+	"unsafe"
 )
 
 type TextureSmoothing int
@@ -28,35 +29,143 @@ const (
 	SmoothingLinear  TextureSmoothing = gl.LINEAR
 )
 
+type TextureFilter int32
+
+const (
+	FilterDefault              TextureFilter = 0 // Follow TextureOptions.Smoothing.
+	FilterNearest              TextureFilter = gl.NEAREST
+	FilterLinear               TextureFilter = gl.LINEAR
+	FilterNearestMipmapNearest TextureFilter = gl.NEAREST_MIPMAP_NEAREST
+	FilterLinearMipmapNearest  TextureFilter = gl.LINEAR_MIPMAP_NEAREST
+	FilterNearestMipmapLinear  TextureFilter = gl.NEAREST_MIPMAP_LINEAR
+	FilterLinearMipmapLinear   TextureFilter = gl.LINEAR_MIPMAP_LINEAR
+)
+
+// withoutMipmap returns the closest filter which doesn't sample mipmaps,
+// since a texture without mipmaps is incomplete under a mipmap filter.
+func (f TextureFilter) withoutMipmap() TextureFilter {
+	switch f {
+	case FilterNearestMipmapNearest, FilterNearestMipmapLinear:
+		return FilterNearest
+	case FilterLinearMipmapNearest, FilterLinearMipmapLinear:
+		return FilterLinear
+	}
+	return f
+}
+
+type TextureWrap int32
+
+const (
+	WrapClamp  TextureWrap = 0 // Clamp to edge.
+	WrapRepeat TextureWrap = gl.REPEAT
+	WrapMirror TextureWrap = gl.MIRRORED_REPEAT
+)
+
+func (w TextureWrap) param() int32 {
+	if w == WrapClamp {
+		return gl.CLAMP_TO_EDGE
+	}
+	return int32(w)
+}
+
+// TextureOptions controls how an image becomes a GL texture.  The zero
+// value clamps at the edges, filters with SmoothingNearest and has no
+// mipmaps.
+type TextureOptions struct {
+	Smoothing TextureSmoothing // Used for whichever of the filters is left at FilterDefault.
+	MinFilter TextureFilter
+	MagFilter TextureFilter
+	WrapS     TextureWrap
+	WrapT     TextureWrap
+	// Mipmaps generates a mipmap chain.  Mipmap min filters fall back to
+	// their plain equivalent when this is false.
+	Mipmaps bool
+	// Anisotropy requests anisotropic filtering with up to this many
+	// samples.  Ignored when it is 1 or less or unsupported, and clamped
+	// to the hardware maximum.
+	Anisotropy float32
+	// PadPow2 uploads the image padded up to power-of-two dimensions, with
+	// the image anchored at the top left.  GL 3.3 handles other sizes
+	// natively, so this is only needed for hardware or techniques that
+	// require it.
+	PadPow2 bool
+	// Premultiply stores color multiplied by alpha, which filters without
+	// dark fringes around transparent edges.  Such textures need the
+	// premultiplied form of each blend mode; the renderer switches to it
+	// automatically.
+	Premultiply bool
+	// PremultipliedSource declares that the image's color is already
+	// premultiplied, as in atlases exported that way, so it is uploaded
+	// without being multiplied again and the texture is marked
+	// premultiplied.
+	PremultipliedSource bool
+}
+
+func (o TextureOptions) premultiplied() bool {
+	return o.Premultiply || o.PremultipliedSource
+}
+
+// pixels returns the bytes to upload for img, see texturePixels.
+func (o TextureOptions) pixels(img image.Image) (pix []byte, rowLength int) {
+	if _, ok := img.(*image.RGBA); o.PremultipliedSource && !ok {
+		// Converting to RGBA would multiply by alpha again, so copy the
+		// color as stored.
+		return texturePixels(img, false)
+	}
+	return texturePixels(img, o.premultiplied())
+}
+
+// Size is always the size of what was uploaded, which is what texture
+// coordinates must be computed against.  OriginalSize is the size of the
+// source image, which is smaller than Size when padded.
 type Texture struct {
 	id           uint32
 	Size         mgl32.Vec2
 	OriginalSize mgl32.Vec2
+	options      TextureOptions
+	bottomUp     bool
 }
 
-func LoadTexture(path string, smoothing TextureSmoothing) (texture *Texture, err error) {
+// BottomUp reports whether the first row of the texture holds the bottom
+// of the picture, as with anything rendered by GL.  Textures made from
+// images store the top row first.
+func (t *Texture) BottomUp() bool {
+	return t.bottomUp
+}
+
+// Premultiplied reports whether color is stored multiplied by alpha.
+func (t *Texture) Premultiplied() bool {
+	return t.options.premultiplied()
+}
+
+func LoadTexture(path string, opts TextureOptions) (texture *Texture, err error) {
 	var img image.Image
-	if img, err = LoadPNG(path); err != nil {
+	if img, err = LoadImage(path); err != nil {
 		return
 	}
-	return GetTexture(img, smoothing)
+	return GetTexture(img, opts)
 }
 
-func GetTexture(img image.Image, smoothing TextureSmoothing) (texture *Texture, err error) {
+func GetTexture(img image.Image, opts TextureOptions) (texture *Texture, err error) {
 	var (
 		originalBounds = img.Bounds()
+		uploaded       = img
+		uploadedBounds image.Rectangle
 		textureId      uint32
-		resizedImg     = getPow2Image(img)
-		resizedBounds  = resizedImg.Bounds()
 	)
-	if textureId, err = getGLTexture(img, smoothing); err != nil {
+	if opts.PadPow2 {
+		uploaded = getPow2Image(img)
+	}
+	uploadedBounds = uploaded.Bounds()
+	if textureId, err = getGLTexture(uploaded, opts); err != nil {
 		return
 	}
 	texture = &Texture{
-		id: textureId,
+		id:      textureId,
+		options: opts,
 		Size: mgl32.Vec2{
-			float32(resizedBounds.Dx()),
-			float32(resizedBounds.Dy()),
+			float32(uploadedBounds.Dx()),
+			float32(uploadedBounds.Dy()),
 		},
 		OriginalSize: mgl32.Vec2{
 			float32(originalBounds.Dx()),
@@ -82,35 +191,159 @@ func (t *Texture) Delete() {
 	}
 }
 
-func getGLTexture(img image.Image, smoothing TextureSmoothing) (t uint32, err error) {
+// Update copies region of img into the same position in the texture, with
+// coordinates measured from the top left of both.  Only the region is
+// transferred, which is much cheaper than recreating the texture when a
+// small part of a large image changes.  Bottom up textures are written
+// flipped so that they stay in GL order.
+func (t *Texture) Update(img image.Image, region image.Rectangle) {
 	var (
-		data   *bytes.Buffer
-		bounds image.Rectangle
-		width  int
-		height int
+		bounds    = img.Bounds()
+		pix       []byte
+		rowLength int
+		y         int
 	)
-	if data, err = imageBytes(img); err != nil {
+	region = region.Add(bounds.Min).Intersect(bounds)
+	if region.Empty() {
 		return
 	}
-	bounds = img.Bounds()
-	width = bounds.Max.X - bounds.Min.X
-	height = bounds.Max.Y - bounds.Min.Y
+	pix, rowLength = t.options.pixels(subImage(img, region))
+	y = region.Min.Y - bounds.Min.Y
+	if t.bottomUp {
+		pix = flipPixels(pix, rowLength, region.Dx(), region.Dy())
+		rowLength = region.Dx()
+		y = int(t.Size.Y()) - (region.Max.Y - bounds.Min.Y)
+	}
+	gl.BindTexture(gl.TEXTURE_2D, t.id)
+	gl.PixelStorei(gl.UNPACK_ROW_LENGTH, int32(rowLength))
+	gl.TexSubImage2D(
+		gl.TEXTURE_2D,
+		0,
+		int32(region.Min.X-bounds.Min.X),
+		int32(y),
+		int32(region.Dx()),
+		int32(region.Dy()),
+		gl.RGBA,
+		gl.UNSIGNED_BYTE,
+		gl.Ptr(&pix[0]),
+	)
+	gl.PixelStorei(gl.UNPACK_ROW_LENGTH, 0)
+	if t.options.Mipmaps {
+		gl.GenerateMipmap(gl.TEXTURE_2D)
+	}
+	gl.BindTexture(gl.TEXTURE_2D, 0)
+}
+
+// Image reads the texture contents back from the GPU in image order.
+func (t *Texture) Image() (img *image.RGBA, err error) {
+	img = image.NewRGBA(image.Rect(0, 0, int(t.Size.X()), int(t.Size.Y())))
+	if len(img.Pix) == 0 {
+		return
+	}
+	gl.BindTexture(gl.TEXTURE_2D, t.id)
+	gl.PixelStorei(gl.PACK_ROW_LENGTH, 0)
+	gl.GetTexImage(gl.TEXTURE_2D, 0, gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(&img.Pix[0]))
+	gl.BindTexture(gl.TEXTURE_2D, 0)
+	if t.bottomUp {
+		flipRows(img)
+	}
+	if e := gl.GetError(); e != 0 {
+		err = fmt.Errorf("OpenGL error reading texture: %X", e)
+	}
+	return
+}
+
+func (o TextureOptions) filters() (min, mag TextureFilter) {
+	var smoothing = TextureFilter(o.Smoothing)
+	if smoothing == FilterDefault {
+		smoothing = FilterNearest
+	}
+	if min = o.MinFilter; min == FilterDefault {
+		min = smoothing
+	}
+	if !o.Mipmaps {
+		min = min.withoutMipmap()
+	}
+	if mag = o.MagFilter.withoutMipmap(); mag == FilterDefault {
+		mag = smoothing
+	}
+	return
+}
+
+func (o TextureOptions) apply() {
+	var min, mag = o.filters()
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, int32(min))
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, int32(mag))
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, o.WrapS.param())
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, o.WrapT.param())
+	if o.Anisotropy > 1 {
+		if max := MaxAnisotropy(); max > 1 {
+			if o.Anisotropy < max {
+				max = o.Anisotropy
+			}
+			gl.TexParameterf(gl.TEXTURE_2D, gl.TEXTURE_MAX_ANISOTROPY, max)
+		}
+	}
+}
+
+var (
+	anisotropyChecked bool
+	anisotropyMax     float32
+)
+
+// MaxAnisotropy returns the largest anisotropic filtering level supported
+// by the current context, or 0 if the extension is unavailable.
+func MaxAnisotropy() float32 {
+	if !anisotropyChecked {
+		anisotropyChecked = true
+		if HasExtension("GL_EXT_texture_filter_anisotropic") || HasExtension("GL_ARB_texture_filter_anisotropic") {
+			gl.GetFloatv(gl.MAX_TEXTURE_MAX_ANISOTROPY, &anisotropyMax)
+		}
+	}
+	return anisotropyMax
+}
+
+func HasExtension(name string) bool {
+	var count int32
+	gl.GetIntegerv(gl.NUM_EXTENSIONS, &count)
+	for i := uint32(0); i < uint32(count); i++ {
+		if gl.GoStr(gl.GetStringi(gl.EXTENSIONS, i)) == name {
+			return true
+		}
+	}
+	return false
+}
+
+func getGLTexture(img image.Image, opts TextureOptions) (t uint32, err error) {
+	var (
+		bounds    = img.Bounds()
+		pix       []byte
+		rowLength int
+		data      unsafe.Pointer
+	)
+	pix, rowLength = opts.pixels(img)
+	if len(pix) > 0 {
+		data = gl.Ptr(&pix[0])
+	}
 	gl.GenTextures(1, &t)
 	gl.BindTexture(gl.TEXTURE_2D, t)
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, int32(smoothing))
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, int32(smoothing))
+	opts.apply()
+	gl.PixelStorei(gl.UNPACK_ROW_LENGTH, int32(rowLength))
 	gl.TexImage2D(
 		gl.TEXTURE_2D,
 		0,
 		gl.RGBA,
-		int32(width),
-		int32(height),
+		int32(bounds.Dx()),
+		int32(bounds.Dy()),
 		0,
 		gl.RGBA,
-		gl.UNSIGNED_INT_8_8_8_8,
-		gl.Ptr(data.Bytes()),
+		gl.UNSIGNED_BYTE,
+		data,
 	)
-	gl.GenerateMipmap(gl.TEXTURE_2D)
+	gl.PixelStorei(gl.UNPACK_ROW_LENGTH, 0)
+	if opts.Mipmaps {
+		gl.GenerateMipmap(gl.TEXTURE_2D)
+	}
 	gl.BindTexture(gl.TEXTURE_2D, 0)
 	return
 }
@@ -70,29 +70,14 @@ func NewTextLoader() *TextLoader {
 	return &TextLoader{}
 }
 
-func (l *TextLoader) add(x, y float32, index int, scale float32, geo *render.Geometry) {
+func (l *TextLoader) add(x, y float32, index int, scale float32, geo *render.Geometry) error {
 	var (
 		findex = float32(index)
 		unit   = scale
 	)
 	x = x * scale
 	y = y * scale
-	geo.Points = append(geo.Points, []render.Point{
-		render.Point{
-			Position: mgl32.Vec3{x, y, 0},
-			Texture:  mgl32.Vec2{0, 0},
-			Frame:    findex,
-		},
-		render.Point{
-			Position: mgl32.Vec3{x + unit, y + unit, 0},
-			Texture:  mgl32.Vec2{1, 1},
-			Frame:    findex,
-		},
-		render.Point{
-			Position: mgl32.Vec3{x, y + unit, 0},
-			Texture:  mgl32.Vec2{0, 1},
-			Frame:    findex,
-		},
+	return geo.AppendQuad([4]render.Point{
 		render.Point{
 			Position: mgl32.Vec3{x, y, 0},
 			Texture:  mgl32.Vec2{0, 0},
@@ -108,7 +93,12 @@ func (l *TextLoader) add(x, y float32, index int, scale float32, geo *render.Geo
 			Texture:  mgl32.Vec2{1, 1},
 			Frame:    findex,
 		},
-	}...)
+		render.Point{
+			Position: mgl32.Vec3{x, y + unit, 0},
+			Texture:  mgl32.Vec2{0, 1},
+			Frame:    findex,
+		},
+	})
 }
 
 func (l *TextLoader) Load(mapping *TextMapping, scale float32, grid string) (geometry *render.Geometry, err error) {
@@ -124,10 +114,15 @@ func (l *TextLoader) Load(mapping *TextMapping, scale float32, grid string) (geo
 		err = fmt.Errorf("No lines in input data")
 		return
 	}
-	geometry = render.NewGeometry(len(lines) * len(lines[0]) * 6)
+	geometry = render.NewIndexedGeometry(
+		len(lines)*len(lines[0])*4,
+		len(lines)*len(lines[0])*len(render.QuadIndices),
+	)
 	for y, line = range lines {
 		for x, char = range line {
-			l.add(float32(x), float32(y), mapping.Get(char), scale, geometry)
+			if err = l.add(float32(x), float32(y), mapping.Get(char), scale, geometry); err != nil {
+				return
+			}
 		}
 	}
 	return
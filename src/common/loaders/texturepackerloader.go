@@ -19,58 +19,66 @@ import (
 	"github.com/go-gl/mathgl/mgl32"
 	"github.com/kurrik/opengl-benchmarks/common/core"
 	"github.com/kurrik/opengl-benchmarks/common/sprites"
+	"image"
 	"io/ioutil"
 	"path"
 )
 
 type texturePackerFloatCoords struct {
-	X float32 `json:x,omitempty`
-	Y float32 `json:y,omitempty`
+	X float32 `json:"x,omitempty"`
+	Y float32 `json:"y,omitempty"`
 }
 
 type texturePackerIntCoords struct {
-	X int `json:x,omitempty`
-	Y int `json:y,omitempty`
-	W int `json:w,omitempty`
-	H int `json:h,omitempty`
+	X int `json:"x,omitempty"`
+	Y int `json:"y,omitempty"`
+	W int `json:"w,omitempty"`
+	H int `json:"h,omitempty"`
 }
 
 type texturePackerFrame struct {
-	Filename         string                   `json:filename`
-	Frame            texturePackerIntCoords   `json:frame`
-	Rotated          bool                     `json:rotated`
-	Trimmed          bool                     `json:trimmed`
-	SpriteSourceSize texturePackerIntCoords   `json:spriteSourceSize`
-	SourceSize       texturePackerIntCoords   `json:sourceSize`
-	Pivot            texturePackerFloatCoords `json:pivot`
+	Filename         string                    `json:"filename"`
+	Frame            texturePackerIntCoords    `json:"frame"`
+	Rotated          bool                      `json:"rotated"`
+	Trimmed          bool                      `json:"trimmed"`
+	SpriteSourceSize texturePackerIntCoords    `json:"spriteSourceSize"`
+	SourceSize       texturePackerIntCoords    `json:"sourceSize"`
+	Pivot            *texturePackerFloatCoords `json:"pivot"`
 }
 
 type texturePackerMeta struct {
-	Image  string                 `json:image`
-	Format string                 `json:format`
-	Size   texturePackerIntCoords `json:size`
-	Scale  string                 `json:scale`
+	Image            string                 `json:"image"`
+	Format           string                 `json:"format"`
+	Size             texturePackerIntCoords `json:"size"`
+	Scale            string                 `json:"scale"`
+	PremultiplyAlpha bool                   `json:"premultiplyAlpha"`
 }
 
 type texturePackerJSONArray struct {
-	Frames []texturePackerFrame `json:frames`
-	Meta   texturePackerMeta    `json:meta`
+	Frames []texturePackerFrame `json:"frames"`
+	Meta   texturePackerMeta    `json:"meta"`
 }
 
 type TexturePackerLoader struct {
+	// AlphaMask names an image, relative to the JSON file, whose brightness
+	// is the opacity of the atlas.  Used for atlases exported as JPEG.
+	AlphaMask string
 }
 
 func NewTexturePackerLoader() *TexturePackerLoader {
 	return &TexturePackerLoader{}
 }
 
-func (l *TexturePackerLoader) Load(jsonPath string, smoothing core.TextureSmoothing) (sheet *sprites.Sheet, err error) {
+func (l *TexturePackerLoader) Load(jsonPath string, opts core.TextureOptions) (sheet *sprites.Sheet, err error) {
 	var (
 		dir         string
 		data        []byte
 		texturePath string
 		parsed      texturePackerJSONArray
 		texture     *core.Texture
+		sprite      *sprites.Sprite
+		img         image.Image
+		mask        image.Image
 	)
 	dir = path.Dir(jsonPath)
 	if data, err = ioutil.ReadFile(jsonPath); err != nil {
@@ -81,7 +89,7 @@ func (l *TexturePackerLoader) Load(jsonPath string, smoothing core.TextureSmooth
 	}
 	sheet = sprites.NewSheet()
 	for _, frame := range parsed.Frames {
-		sheet.AddSprite(
+		sprite = sheet.AddSprite(
 			frame.Filename,
 			mgl32.Vec2{
 				float32(frame.Frame.W),
@@ -92,9 +100,27 @@ func (l *TexturePackerLoader) Load(jsonPath string, smoothing core.TextureSmooth
 				float32(frame.Frame.Y),
 			},
 		)
+		if frame.Pivot != nil {
+			// TexturePacker measures the pivot from the top left.
+			sprite.SetPivot(mgl32.Vec2{frame.Pivot.X, 1.0 - frame.Pivot.Y})
+		}
 	}
 	texturePath = path.Join(dir, parsed.Meta.Image)
-	if texture, err = core.LoadTexture(texturePath, smoothing); err != nil {
+	if img, err = core.LoadImage(texturePath); err != nil {
+		return
+	}
+	if l.AlphaMask != "" {
+		if mask, err = core.LoadImage(path.Join(dir, l.AlphaMask)); err != nil {
+			return
+		}
+		if img, err = core.ApplyAlphaMask(img, mask); err != nil {
+			return
+		}
+	}
+	if parsed.Meta.PremultiplyAlpha {
+		opts.PremultipliedSource = true
+	}
+	if texture, err = core.GetTexture(img, opts); err != nil {
 		return
 	}
 	sheet.SetTexture(texture)
@@ -0,0 +1,267 @@
+// Copyright 2016 Arne Roomann-Kurrik
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postfx
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/kurrik/opengl-benchmarks/common/core"
+)
+
+const COLOR_GRADE_FRAGMENT = PASS_HEADER + `
+uniform float u_Brightness;
+uniform float u_Contrast;
+uniform float u_Saturation;
+uniform float u_Gamma;
+uniform vec3 u_Tint;
+
+void main() {
+  vec4 c = texture(u_Texture, v_UV);
+  vec3 rgb = c.rgb * u_Tint + u_Brightness;
+  rgb = (rgb - 0.5) * u_Contrast + 0.5;
+  float luma = dot(rgb, vec3(0.2126, 0.7152, 0.0722));
+  rgb = mix(vec3(luma), rgb, u_Saturation);
+  rgb = pow(max(rgb, 0.0), vec3(1.0 / u_Gamma));
+  v_FragData = vec4(rgb, c.a);
+}`
+
+const VIGNETTE_FRAGMENT = PASS_HEADER + `
+uniform float u_Radius;
+uniform float u_Softness;
+uniform float u_Strength;
+
+void main() {
+  vec4 c = texture(u_Texture, v_UV);
+  float d = length(v_UV - 0.5) * 1.41421;
+  float v = smoothstep(u_Radius, u_Radius - u_Softness, d);
+  v_FragData = vec4(c.rgb * mix(1.0, v, u_Strength), c.a);
+}`
+
+const CRT_FRAGMENT = PASS_HEADER + `
+uniform float u_Curvature;
+uniform float u_Scanlines;
+
+void main() {
+  vec2 uv = v_UV * 2.0 - 1.0;
+  uv *= 1.0 + u_Curvature * dot(uv.yx, uv.yx);
+  uv = uv * 0.5 + 0.5;
+  if (uv.x < 0.0 || uv.x > 1.0 || uv.y < 0.0 || uv.y > 1.0) {
+    v_FragData = vec4(0.0, 0.0, 0.0, 1.0);
+    return;
+  }
+  vec4 c = texture(u_Texture, uv);
+  float line = 0.5 + 0.5 * sin(uv.y * u_Resolution.y * 3.14159);
+  c.rgb *= mix(1.0, line, u_Scanlines);
+  v_FragData = c;
+}`
+
+const PIXELATE_FRAGMENT = PASS_HEADER + `
+uniform float u_PixelSize;
+
+void main() {
+  vec2 cell = u_Texel * u_PixelSize;
+  vec2 uv = (floor(v_UV / cell) + 0.5) * cell;
+  v_FragData = texture(u_Texture, uv);
+}`
+
+// BLUR_FRAGMENT is one direction of a separable 9 tap gaussian.
+const BLUR_FRAGMENT = PASS_HEADER + `
+uniform vec2 u_Direction;
+
+const float weights[5] = float[](0.2270270, 0.1945946, 0.1216216, 0.0540541, 0.0162162);
+
+void main() {
+  vec2 step = u_Direction * u_Texel;
+  vec4 c = texture(u_Texture, v_UV) * weights[0];
+  for (int i = 1; i < 5; i++) {
+    c += texture(u_Texture, v_UV + step * float(i)) * weights[i];
+    c += texture(u_Texture, v_UV - step * float(i)) * weights[i];
+  }
+  v_FragData = c;
+}`
+
+const BRIGHT_FRAGMENT = PASS_HEADER + `
+uniform float u_Threshold;
+
+void main() {
+  vec4 c = texture(u_Texture, v_UV);
+  float luma = dot(c.rgb, vec3(0.2126, 0.7152, 0.0722));
+  v_FragData = vec4(c.rgb * smoothstep(u_Threshold, u_Threshold + 0.1, luma), 1.0);
+}`
+
+const BLOOM_COMBINE_FRAGMENT = PASS_HEADER + `
+uniform sampler2D u_Bloom;
+uniform float u_Intensity;
+
+void main() {
+  vec4 c = texture(u_Texture, v_UV);
+  v_FragData = vec4(c.rgb + texture(u_Bloom, v_UV).rgb * u_Intensity, c.a);
+}`
+
+// NewColorGrade adjusts u_Brightness (added, default 0), u_Contrast,
+// u_Saturation, u_Gamma (defaults 1) and u_Tint (multiplied, default white).
+func NewColorGrade() (p *Pass, err error) {
+	if p, err = NewPass(COLOR_GRADE_FRAGMENT); err != nil {
+		return
+	}
+	p.SetFloat("u_Brightness", 0)
+	p.SetFloat("u_Contrast", 1)
+	p.SetFloat("u_Saturation", 1)
+	p.SetFloat("u_Gamma", 1)
+	p.SetVec3("u_Tint", mgl32.Vec3{1, 1, 1})
+	return
+}
+
+// NewVignette darkens toward the corners.  Uniforms are u_Radius where
+// darkening ends, u_Softness of the falloff and u_Strength.
+func NewVignette() (p *Pass, err error) {
+	if p, err = NewPass(VIGNETTE_FRAGMENT); err != nil {
+		return
+	}
+	p.SetFloat("u_Radius", 1.0)
+	p.SetFloat("u_Softness", 0.6)
+	p.SetFloat("u_Strength", 0.8)
+	return
+}
+
+// NewCRT bends the image like a tube screen and adds scanlines.  Uniforms
+// are u_Curvature and u_Scanlines, the scanline intensity.
+func NewCRT() (p *Pass, err error) {
+	if p, err = NewPass(CRT_FRAGMENT); err != nil {
+		return
+	}
+	p.SetFloat("u_Curvature", 0.05)
+	p.SetFloat("u_Scanlines", 0.3)
+	return
+}
+
+// NewPixelate snaps the image to blocks of u_PixelSize pixels.
+func NewPixelate(size float32) (p *Pass, err error) {
+	if p, err = NewPass(PIXELATE_FRAGMENT); err != nil {
+		return
+	}
+	p.SetFloat("u_PixelSize", size)
+	return
+}
+
+// Blur is a gaussian blur run as a horizontal then a vertical pass.
+type Blur struct {
+	toggle
+	horizontal *Pass
+	vertical   *Pass
+	scratch    *core.Framebuffer
+}
+
+// NewBlur creates a blur whose radius grows with spread, measured in
+// texels between samples.
+func NewBlur(spread float32) (b *Blur, err error) {
+	b = &Blur{}
+	if b.horizontal, err = NewPass(BLUR_FRAGMENT); err != nil {
+		return
+	}
+	if b.vertical, err = NewPass(BLUR_FRAGMENT); err != nil {
+		return
+	}
+	b.SetSpread(spread)
+	return
+}
+
+func (b *Blur) SetSpread(spread float32) {
+	b.horizontal.SetVec2("u_Direction", mgl32.Vec2{spread, 0})
+	b.vertical.SetVec2("u_Direction", mgl32.Vec2{0, spread})
+}
+
+// Apply blurs input into output.  The intermediate is the size of input,
+// so a smaller input blurs more cheaply.
+func (b *Blur) Apply(p *Pipeline, input *core.Texture, output *core.Framebuffer) (err error) {
+	if b.scratch, err = p.fit(b.scratch, input.Size, 1); err != nil {
+		return
+	}
+	if err = b.horizontal.Apply(p, input, b.scratch); err != nil {
+		return
+	}
+	return b.vertical.Apply(p, b.scratch.Texture(), output)
+}
+
+func (b *Blur) Delete() {
+	b.horizontal.Delete()
+	b.vertical.Delete()
+	if b.scratch != nil {
+		b.scratch.Delete()
+		b.scratch = nil
+	}
+}
+
+// Bloom makes bright areas glow.  Pixels brighter than the threshold are
+// extracted at half resolution, blurred and added back onto the image.
+type Bloom struct {
+	toggle
+	bright  *Pass
+	blur    *Blur
+	combine *Pass
+	extract *core.Framebuffer
+	blurred *core.Framebuffer
+}
+
+func NewBloom(threshold, intensity float32) (b *Bloom, err error) {
+	b = &Bloom{}
+	if b.bright, err = NewPass(BRIGHT_FRAGMENT); err != nil {
+		return
+	}
+	if b.blur, err = NewBlur(1.5); err != nil {
+		return
+	}
+	if b.combine, err = NewPass(BLOOM_COMBINE_FRAGMENT); err != nil {
+		return
+	}
+	b.SetThreshold(threshold)
+	b.SetIntensity(intensity)
+	return
+}
+
+func (b *Bloom) SetThreshold(threshold float32) {
+	b.bright.SetFloat("u_Threshold", threshold)
+}
+
+func (b *Bloom) SetIntensity(intensity float32) {
+	b.combine.SetFloat("u_Intensity", intensity)
+}
+
+func (b *Bloom) Apply(p *Pipeline, input *core.Texture, output *core.Framebuffer) (err error) {
+	if b.extract, err = p.fit(b.extract, input.Size, 2); err != nil {
+		return
+	}
+	if b.blurred, err = p.fit(b.blurred, input.Size, 2); err != nil {
+		return
+	}
+	if err = b.bright.Apply(p, input, b.extract); err != nil {
+		return
+	}
+	if err = b.blur.Apply(p, b.extract.Texture(), b.blurred); err != nil {
+		return
+	}
+	b.combine.SetTexture("u_Bloom", 1, b.blurred.Texture())
+	return b.combine.Apply(p, input, output)
+}
+
+func (b *Bloom) Delete() {
+	b.bright.Delete()
+	b.blur.Delete()
+	b.combine.Delete()
+	for _, buffer := range []*core.Framebuffer{b.extract, b.blurred} {
+		if buffer != nil {
+			buffer.Delete()
+		}
+	}
+}
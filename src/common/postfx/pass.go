@@ -0,0 +1,185 @@
+// Copyright 2016 Arne Roomann-Kurrik
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postfx
+
+import (
+	"fmt"
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/kurrik/opengl-benchmarks/common/core"
+)
+
+const PASS_VERTEX = `#version 150
+
+in vec3 v_Position;
+in vec2 v_Texture;
+out vec2 v_UV;
+
+void main() {
+  v_UV = v_Texture;
+  gl_Position = vec4(v_Position, 1.0);
+}`
+
+// PASS_HEADER declares everything a pass receives.  Custom fragment
+// shaders can start with it and write the result to v_FragData.
+const PASS_HEADER = `#version 150
+
+precision mediump float;
+
+in vec2 v_UV;
+uniform sampler2D u_Texture;
+uniform vec2 u_Resolution;
+uniform vec2 u_Texel;
+uniform float u_Time;
+out vec4 v_FragData;
+`
+
+// Effect is one step in a Pipeline.  Apply reads input and draws into
+// output, or into the default framebuffer when output is nil.
+type Effect interface {
+	Apply(p *Pipeline, input *core.Texture, output *core.Framebuffer) error
+	Enabled() bool
+	SetEnabled(enabled bool)
+	Delete()
+}
+
+type toggle struct {
+	disabled bool
+}
+
+func (t *toggle) Enabled() bool {
+	return !t.disabled
+}
+
+func (t *toggle) SetEnabled(enabled bool) {
+	t.disabled = !enabled
+}
+
+type passTexture struct {
+	unit    int32
+	texture *core.Texture
+}
+
+// Pass is a single full-screen shader.  Uniforms set on it are remembered
+// and applied every time it runs.
+type Pass struct {
+	toggle
+	program     *core.Program
+	uTexture    *core.Uniform
+	uResolution *core.Uniform
+	uTexel      *core.Uniform
+	uTime       *core.Uniform
+	values      map[string]func(u *core.Uniform)
+	uniforms    map[string]*core.Uniform
+	textures    map[string]passTexture
+}
+
+// NewPass compiles fragment, which should begin with PASS_HEADER.
+func NewPass(fragment string) (p *Pass, err error) {
+	p = &Pass{
+		program:  core.NewProgram(),
+		values:   map[string]func(u *core.Uniform){},
+		uniforms: map[string]*core.Uniform{},
+		textures: map[string]passTexture{},
+	}
+	if err = p.program.Load(PASS_VERTEX, fragment); err != nil {
+		return
+	}
+	p.uTexture = p.program.Uniform("u_Texture")
+	p.uResolution = p.program.Uniform("u_Resolution")
+	p.uTexel = p.program.Uniform("u_Texel")
+	p.uTime = p.program.Uniform("u_Time")
+	return
+}
+
+func (p *Pass) uniform(name string) (u *core.Uniform) {
+	var exists bool
+	if u, exists = p.uniforms[name]; !exists {
+		u = p.program.Uniform(name)
+		p.uniforms[name] = u
+	}
+	return
+}
+
+func (p *Pass) SetInt(name string, v int32) {
+	p.values[name] = func(u *core.Uniform) { u.Int(v) }
+}
+
+func (p *Pass) SetFloat(name string, v float32) {
+	p.values[name] = func(u *core.Uniform) { u.Float(v) }
+}
+
+func (p *Pass) SetVec2(name string, v mgl32.Vec2) {
+	p.values[name] = func(u *core.Uniform) { u.Vec2(v) }
+}
+
+func (p *Pass) SetVec3(name string, v mgl32.Vec3) {
+	p.values[name] = func(u *core.Uniform) { u.Vec3(v) }
+}
+
+func (p *Pass) SetVec4(name string, v mgl32.Vec4) {
+	p.values[name] = func(u *core.Uniform) { u.Vec4(v) }
+}
+
+// SetTexture binds an additional texture to the named sampler.  Unit 0 is
+// reserved for the pass input.
+func (p *Pass) SetTexture(name string, unit int32, texture *core.Texture) {
+	p.textures[name] = passTexture{unit, texture}
+	p.SetInt(name, unit)
+}
+
+func (p *Pass) Apply(pipeline *Pipeline, input *core.Texture, output *core.Framebuffer) (err error) {
+	if output != nil {
+		output.Bind()
+		defer output.Unbind()
+	}
+	var size = pipeline.Size()
+	if output != nil {
+		size = output.Texture().Size
+	}
+	p.program.Bind()
+	for _, t := range p.textures {
+		gl.ActiveTexture(gl.TEXTURE0 + uint32(t.unit))
+		t.texture.Bind()
+	}
+	gl.ActiveTexture(gl.TEXTURE0)
+	input.Bind()
+	p.uTexture.Int(0)
+	p.uResolution.Vec2(size)
+	p.uTexel.Vec2(mgl32.Vec2{1.0 / input.Size.X(), 1.0 / input.Size.Y()})
+	p.uTime.Float(pipeline.Time)
+	for name, set := range p.values {
+		set(p.uniform(name))
+	}
+	pipeline.draw(p.program)
+	if e := gl.GetError(); e != 0 {
+		err = fmt.Errorf("OpenGL error in pass: %X", e)
+	}
+	for _, t := range p.textures {
+		gl.ActiveTexture(gl.TEXTURE0 + uint32(t.unit))
+		t.texture.Unbind()
+	}
+	gl.ActiveTexture(gl.TEXTURE0)
+	input.Unbind()
+	p.program.Unbind()
+	return
+}
+
+func (p *Pass) Delete() {
+	if p.program != nil {
+		p.program.Delete()
+		p.program = nil
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright 2016 Arne Roomann-Kurrik
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postfx
+
+import (
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/kurrik/opengl-benchmarks/common/core"
+	"github.com/kurrik/opengl-benchmarks/common/render"
+	"unsafe"
+)
+
+const COPY_FRAGMENT = PASS_HEADER + `
+void main() {
+  v_FragData = texture(u_Texture, v_UV);
+}`
+
+// Pipeline applies a chain of effects to the scene.  Draw the scene
+// between Begin and End; End runs each enabled effect on the output of the
+// previous one, alternating between two framebuffers, and the last effect
+// draws to the screen.
+type Pipeline struct {
+	Time     float32 // Seconds, passed to every pass as u_Time.
+	width    int
+	height   int
+	format   core.ColorFormat
+	buffers  [2]*core.Framebuffer
+	effects  []Effect
+	triangle *render.Geometry
+	copy     *Pass
+	blend    *core.BlendState
+}
+
+func NewPipeline(w, h int, format core.ColorFormat) (p *Pipeline, err error) {
+	p = &Pipeline{
+		width:    w,
+		height:   h,
+		format:   format,
+		triangle: render.NewGeometryFromPoints(render.FullscreenTriangle),
+	}
+	if p.copy, err = NewPass(COPY_FRAGMENT); err != nil {
+		return
+	}
+	for i := range p.buffers {
+		if p.buffers[i], err = p.newFramebuffer(w, h); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// newFramebuffer creates an intermediate target matching the pipeline's
+// color format.  Effects use it for their own scratch buffers.
+func (p *Pipeline) newFramebuffer(w, h int) (*core.Framebuffer, error) {
+	return core.NewFramebuffer(w, h, core.FramebufferOptions{
+		Colors:  []core.ColorFormat{p.format},
+		Depth:   true,
+		Texture: core.TextureOptions{Smoothing: core.SmoothingLinear},
+	})
+}
+
+// fit returns buffer, created or resized as needed to be size divided by
+// scale.  Effects use it to manage their scratch framebuffers.
+func (p *Pipeline) fit(buffer *core.Framebuffer, size mgl32.Vec2, scale int) (out *core.Framebuffer, err error) {
+	var w, h = int(size.X()) / scale, int(size.Y()) / scale
+	if w < 1 {
+		w = 1
+	}
+	if h < 1 {
+		h = 1
+	}
+	out = buffer
+	if out == nil {
+		out, err = p.newFramebuffer(w, h)
+	} else if out.Width != w || out.Height != h {
+		err = out.Resize(w, h)
+	}
+	return
+}
+
+// SetBlendState lets the pipeline disable blending while effects run and
+// restore the previous mode afterwards.
+func (p *Pipeline) SetBlendState(state *core.BlendState) {
+	p.blend = state
+}
+
+func (p *Pipeline) Size() mgl32.Vec2 {
+	return mgl32.Vec2{float32(p.width), float32(p.height)}
+}
+
+func (p *Pipeline) Resize(w, h int) (err error) {
+	p.width = w
+	p.height = h
+	for _, buffer := range p.buffers {
+		if err = buffer.Resize(w, h); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (p *Pipeline) Add(effect Effect) {
+	p.effects = append(p.effects, effect)
+}
+
+func (p *Pipeline) Effects() []Effect {
+	return p.effects
+}
+
+// Begin redirects drawing into the pipeline.
+func (p *Pipeline) Begin() {
+	p.buffers[0].Bind()
+	p.buffers[0].Clear()
+}
+
+// End applies the effects and draws the result to whatever was bound
+// before Begin.
+func (p *Pipeline) End() (err error) {
+	var (
+		input   = p.buffers[0].Texture()
+		current = 0
+		active  []Effect
+		mode    core.BlendMode
+		premult bool
+	)
+	p.buffers[0].Unbind()
+	for _, effect := range p.effects {
+		if effect.Enabled() {
+			active = append(active, effect)
+		}
+	}
+	if len(active) == 0 {
+		active = append(active, p.copy)
+	}
+	if p.blend != nil {
+		mode, premult = p.blend.Mode(), p.blend.Premultiplied()
+		p.blend.Apply(core.BlendOpaque)
+	}
+	for i, effect := range active {
+		var output *core.Framebuffer
+		if i < len(active)-1 {
+			current = (current + 1) % len(p.buffers)
+			output = p.buffers[current]
+		}
+		if err = effect.Apply(p, input, output); err != nil {
+			break
+		}
+		if output != nil {
+			input = output.Texture()
+		}
+	}
+	if p.blend != nil {
+		p.blend.ApplyPremultiplied(mode, premult)
+	}
+	return
+}
+
+func (p *Pipeline) draw(program *core.Program) {
+	var pt render.Point
+	p.triangle.Bind()
+	p.triangle.Upload()
+	program.Attrib("v_Position", unsafe.Sizeof(pt)).Vec3(unsafe.Offsetof(pt.Position), 0)
+	program.Attrib("v_Texture", unsafe.Sizeof(pt)).Vec2(unsafe.Offsetof(pt.Texture), 0)
+	gl.DrawArrays(gl.TRIANGLES, 0, int32(len(p.triangle.Points)))
+}
+
+// Delete frees the pipeline and every effect added to it.
+func (p *Pipeline) Delete() {
+	for _, effect := range p.effects {
+		effect.Delete()
+	}
+	p.effects = nil
+	for i, buffer := range p.buffers {
+		if buffer != nil {
+			buffer.Delete()
+			p.buffers[i] = nil
+		}
+	}
+	if p.copy != nil {
+		p.copy.Delete()
+		p.copy = nil
+	}
+	p.triangle.Delete()
+}
@@ -15,6 +15,7 @@
 package render
 
 import (
+	"fmt"
 	"github.com/go-gl/mathgl/mgl32"
 	"github.com/kurrik/opengl-benchmarks/common/core"
 	"unsafe"
@@ -59,11 +60,61 @@ var Square = []Point{
 	},
 }
 
+var SquareIndexed = []Point{
+	Point{
+		Position: mgl32.Vec3{-0.5, -0.5, 0},
+		Texture:  mgl32.Vec2{0, 0},
+		Frame:    0,
+	},
+	Point{
+		Position: mgl32.Vec3{0.5, -0.5, 0},
+		Texture:  mgl32.Vec2{1, 0},
+		Frame:    0,
+	},
+	Point{
+		Position: mgl32.Vec3{0.5, 0.5, 0},
+		Texture:  mgl32.Vec2{1, 1},
+		Frame:    0,
+	},
+	Point{
+		Position: mgl32.Vec3{-0.5, 0.5, 0},
+		Texture:  mgl32.Vec2{0, 1},
+		Frame:    0,
+	},
+}
+
+// FullscreenTriangle covers all of clip space with a single triangle,
+// which avoids the seam and overdraw along the diagonal of a two triangle
+// quad.  Texture coordinates run from 0 to 1 across the visible area.
+var FullscreenTriangle = []Point{
+	Point{
+		Position: mgl32.Vec3{-1, -1, 0},
+		Texture:  mgl32.Vec2{0, 0},
+		Frame:    0,
+	},
+	Point{
+		Position: mgl32.Vec3{3, -1, 0},
+		Texture:  mgl32.Vec2{2, 0},
+		Frame:    0,
+	},
+	Point{
+		Position: mgl32.Vec3{-1, 3, 0},
+		Texture:  mgl32.Vec2{0, 2},
+		Frame:    0,
+	},
+}
+
+// QuadIndices lists the triangles for a quad whose points are ordered
+// bottom left, bottom right, top right, top left.
+var QuadIndices = []uint32{0, 2, 3, 0, 1, 2}
+
 type Geometry struct {
-	Points []Point
-	Dirty  bool
-	vbo    *core.ArrayBuffer
-	stride uintptr
+	Points  []Point
+	Indices []uint32
+	Dirty   bool
+	vbo     *core.ArrayBuffer
+	ibo     *core.ElementArrayBuffer
+	stride  uintptr
 }
 
 func NewGeometry(capacity int) (out *Geometry) {
@@ -86,8 +137,46 @@ func NewGeometryFromPoints(points []Point) (out *Geometry) {
 	return
 }
 
+func NewIndexedGeometry(capacity, indexCapacity int) (out *Geometry) {
+	out = NewGeometry(capacity)
+	out.Indices = make([]uint32, 0, indexCapacity)
+	out.ibo = core.NewElementArrayBuffer()
+	return
+}
+
+func NewGeometryFromIndexedPoints(points []Point, indices []uint32) (out *Geometry) {
+	out = NewIndexedGeometry(len(points), len(indices))
+	out.Points = append(out.Points, points...)
+	out.Indices = append(out.Indices, indices...)
+	return
+}
+
+// AppendQuad adds four points ordered as in SquareIndexed along with the
+// indices needed to draw them.  The geometry must have been created with an
+// index buffer.
+func (g *Geometry) AppendQuad(points [4]Point) (err error) {
+	if g.ibo == nil {
+		err = fmt.Errorf("Cannot append a quad to geometry without indices")
+		return
+	}
+	var base = uint32(len(g.Points))
+	g.Points = append(g.Points, points[:]...)
+	for _, index := range QuadIndices {
+		g.Indices = append(g.Indices, base+index)
+	}
+	g.Dirty = true
+	return
+}
+
+func (g *Geometry) Indexed() bool {
+	return g.ibo != nil
+}
+
 func (g *Geometry) Bind() {
 	g.vbo.Bind()
+	if g.ibo != nil {
+		g.ibo.Bind()
+	}
 }
 
 func (g *Geometry) Delete() {
@@ -95,11 +184,18 @@ func (g *Geometry) Delete() {
 		g.vbo.Delete()
 		g.vbo = nil
 	}
+	if g.ibo != nil {
+		g.ibo.Delete()
+		g.ibo = nil
+	}
 }
 
 func (g *Geometry) Upload() {
 	if g.Dirty {
 		g.vbo.Upload(g.Points, len(g.Points)*int(g.stride))
+		if g.ibo != nil && len(g.Indices) > 0 {
+			g.ibo.Upload(g.Indices, len(g.Indices)*4)
+		}
 		g.Dirty = false
 	}
 }
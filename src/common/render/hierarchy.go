@@ -0,0 +1,143 @@
+// Copyright 2016 Arne Roomann-Kurrik
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package render
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// hierarchy holds the scene graph links for an Instance.  Parents and
+// children may live in different instance lists; draw order is still
+// decided by the lists.
+type hierarchy struct {
+	parent      *Instance
+	children    []*Instance
+	world       mgl32.Mat4
+	worldDirty  bool
+	inheritTint bool
+}
+
+func (i *Instance) Parent() *Instance {
+	return i.parent
+}
+
+func (i *Instance) Children() []*Instance {
+	return i.children
+}
+
+// SetParent attaches the instance below parent so that it inherits the
+// parent's transform.  Passing nil detaches it.  Attaching an instance
+// below one of its own descendants is ignored.
+func (i *Instance) SetParent(parent *Instance) {
+	if parent == i.parent {
+		return
+	}
+	for p := parent; p != nil; p = p.parent {
+		if p == i {
+			return
+		}
+	}
+	if i.parent != nil {
+		var siblings = i.parent.children
+		for j, child := range siblings {
+			if child == i {
+				copy(siblings[j:], siblings[j+1:])
+				siblings[len(siblings)-1] = nil
+				i.parent.children = siblings[:len(siblings)-1]
+				break
+			}
+		}
+	}
+	i.parent = parent
+	if parent != nil {
+		parent.children = append(parent.children, i)
+	}
+	i.markDirty()
+}
+
+func (i *Instance) AddChild(child *Instance) {
+	if child != nil {
+		child.SetParent(i)
+	}
+}
+
+// detachHierarchy unlinks the instance from its parent and children.  The
+// children become roots positioned by their local transforms alone.
+func (i *Instance) detachHierarchy() {
+	i.SetParent(nil)
+	for len(i.children) > 0 {
+		i.children[len(i.children)-1].SetParent(nil)
+	}
+}
+
+// SetInheritTint makes the instance multiply its tint (and so its opacity)
+// with that of its ancestors.
+func (i *Instance) SetInheritTint(inherit bool) {
+	if i.inheritTint != inherit {
+		i.inheritTint = inherit
+		i.version++
+	}
+}
+
+// WorldTint returns the tint after applying inherited ancestor tints.
+func (i *Instance) WorldTint() mgl32.Vec4 {
+	if !i.inheritTint || i.parent == nil {
+		return i.tint
+	}
+	var parent = i.parent.WorldTint()
+	return mgl32.Vec4{
+		i.tint[0] * parent[0],
+		i.tint[1] * parent[1],
+		i.tint[2] * parent[2],
+		i.tint[3] * parent[3],
+	}
+}
+
+// WorldVisible reports whether the instance and all of its ancestors are
+// visible.
+func (i *Instance) WorldVisible() bool {
+	for inst := i; inst != nil; inst = inst.parent {
+		if inst.hidden {
+			return false
+		}
+	}
+	return true
+}
+
+// markDirty flags the local transform as stale and the world transforms
+// of the whole subtree as needing recomputation.
+func (i *Instance) markDirty() {
+	i.dirty = true
+	i.markWorldDirty()
+}
+
+func (i *Instance) markWorldDirty() {
+	i.worldDirty = true
+	i.version++
+	for _, child := range i.children {
+		if !child.worldDirty {
+			child.markWorldDirty()
+		}
+	}
+}
+
+func (i *Instance) markTintChanged() {
+	i.version++
+	for _, child := range i.children {
+		if child.inheritTint {
+			child.markTintChanged()
+		}
+	}
+}
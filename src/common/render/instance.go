@@ -16,6 +16,7 @@ package render
 
 import (
 	"github.com/go-gl/mathgl/mgl32"
+	"math"
 )
 
 type Instance struct {
@@ -23,47 +24,151 @@ type Instance struct {
 	position mgl32.Vec3
 	scale    mgl32.Vec3
 	rotation float32
+	pivot    mgl32.Vec2 // Unit coordinates, (0,0) bottom left.
+	pivotSet bool       // Pivot was chosen by SetPivot rather than a default.
+	skew     mgl32.Vec2 // Degrees.
+	flipX    bool
+	flipY    bool
+	hidden   bool
 	Frame    int
-	Key      string // TODO: move to an interface{} data pointer.
+	Data     interface{} // Arbitrary payload for game code.
+	frameKey string      // Sheet key, maintained by sprite and text lists.
+	text     string      // Text content, maintained by text lists.
 	color    mgl32.Vec4
+	tint     mgl32.Vec4
 	dirty    bool
-	next     *Instance
-	prev     *Instance
-	list     Instances
+	version  uint32 // Incremented on every change that affects rendering.
+	hierarchy
+	next  *Instance
+	prev  *Instance
+	list  Instances
+	pool  *InstancePool // Pool whose pages hold this instance, if any.
+	id    int           // Storage slot when allocated by a pool, else -1.
+	index int           // Dense position in a pool, or the next free slot.
 }
 
-func newInstance() *Instance {
-	return &Instance{
+func newInstance() (i *Instance) {
+	i = &Instance{}
+	i.reset(-1)
+	return
+}
+
+func (i *Instance) reset(id int) {
+	// Keep counting versions across reuse so that retained storage which
+	// still refers to this memory sees it as changed.
+	var version = i.version + 1
+	*i = Instance{
 		scale:    mgl32.Vec3{1.0, 1.0, 1.0},
 		position: mgl32.Vec3{0.0, 0.0, 0.0},
 		color:    mgl32.Vec4{0.0, 0.0, 0.0, 0.0},
+		tint:     mgl32.Vec4{1.0, 1.0, 1.0, 1.0},
 		rotation: 0,
+		pivot:    mgl32.Vec2{0.5, 0.5},
 		dirty:    true,
+		version:  version,
+		id:       id,
 	}
 }
 
+func (i *Instance) Scale() mgl32.Vec3 {
+	return i.scale
+}
+
+func (i *Instance) Position() mgl32.Vec3 {
+	return i.position
+}
+
+func (i *Instance) Rotation() float32 {
+	return i.rotation
+}
+
 func (i *Instance) SetScale(s mgl32.Vec3) {
 	if i.scale.X() != s.X() || i.scale.Y() != s.Y() || i.scale.Z() != s.Z() {
 		i.scale = s
-		i.dirty = true
+		i.markDirty()
 	}
 }
 
 func (i *Instance) SetPosition(p mgl32.Vec3) {
 	if i.position.X() != p.X() || i.position.Y() != p.Y() || i.position.Z() != p.Z() {
 		i.position = p
-		i.dirty = true
+		i.markDirty()
 	}
 }
 
 func (i *Instance) SetRotation(r float32) {
 	if i.rotation != r {
 		i.rotation = r
-		i.dirty = true
+		i.markDirty()
+	}
+}
+
+// SetPivot sets the point, in unit coordinates of the quad with (0,0) at
+// the bottom left, that is placed at the instance position and about which
+// it rotates, scales, skews and flips.  A pivot set here is kept when
+// SetDefaultPivot is called later.
+func (i *Instance) SetPivot(p mgl32.Vec2) {
+	i.pivotSet = true
+	if i.pivot != p {
+		i.pivot = p
+		i.markDirty()
+	}
+}
+
+// SetDefaultPivot sets the pivot unless one was chosen with SetPivot, as
+// sprite lists do with each sprite's pivot when the frame changes.
+func (i *Instance) SetDefaultPivot(p mgl32.Vec2) {
+	if !i.pivotSet && i.pivot != p {
+		i.pivot = p
+		i.markDirty()
+	}
+}
+
+func (i *Instance) Pivot() mgl32.Vec2 {
+	return i.pivot
+}
+
+// SetSkew shears the instance by the given angles in degrees along the X
+// and Y axes.
+func (i *Instance) SetSkew(s mgl32.Vec2) {
+	if i.skew != s {
+		i.skew = s
+		i.markDirty()
 	}
 }
 
+func (i *Instance) Skew() mgl32.Vec2 {
+	return i.skew
+}
+
+func (i *Instance) SetFlip(x, y bool) {
+	if i.flipX != x || i.flipY != y {
+		i.flipX = x
+		i.flipY = y
+		i.markDirty()
+	}
+}
+
+func (i *Instance) Flip() (x, y bool) {
+	return i.flipX, i.flipY
+}
+
+// GetModel returns the world transform, which includes the transforms of
+// any ancestors.
 func (i *Instance) GetModel() mgl32.Mat4 {
+	if i.parent == nil {
+		return i.LocalModel()
+	}
+	if i.worldDirty {
+		i.world = i.parent.GetModel().Mul4(i.LocalModel())
+		i.worldDirty = false
+	}
+	return i.world
+}
+
+// LocalModel returns the transform built from this instance's own
+// position, rotation and scale.
+func (i *Instance) LocalModel() mgl32.Mat4 {
 	if i.dirty {
 		var model mgl32.Mat4
 		model = mgl32.Translate3D(
@@ -72,30 +177,134 @@ func (i *Instance) GetModel() mgl32.Mat4 {
 			i.position.Z(),
 		)
 		model = model.Mul4(mgl32.HomogRotate3DZ(mgl32.DegToRad(i.rotation)))
-		model = model.Mul4(mgl32.Scale3D(i.scale.X(), i.scale.Y(), i.scale.Z()))
+		if i.skew[0] != 0 || i.skew[1] != 0 {
+			var shear = mgl32.Ident4()
+			shear.Set(0, 1, float32(math.Tan(float64(mgl32.DegToRad(i.skew[0])))))
+			shear.Set(1, 0, float32(math.Tan(float64(mgl32.DegToRad(i.skew[1])))))
+			model = model.Mul4(shear)
+		}
+		var scale = i.scale
+		if i.flipX {
+			scale[0] = -scale[0]
+		}
+		if i.flipY {
+			scale[1] = -scale[1]
+		}
+		model = model.Mul4(mgl32.Scale3D(scale.X(), scale.Y(), scale.Z()))
+		if i.pivot[0] != 0.5 || i.pivot[1] != 0.5 {
+			// Geometry is centered on the origin, so shift the pivot there.
+			model = model.Mul4(mgl32.Translate3D(0.5-i.pivot[0], 0.5-i.pivot[1], 0))
+		}
 		i.model = model
 		i.dirty = false
 	}
 	return i.model
 }
 
+func (i *Instance) Visible() bool {
+	return !i.hidden
+}
+
+// SetVisible hides or shows the instance (and its children) without
+// removing it from its list, so it keeps its place in draw order.
+func (i *Instance) SetVisible(visible bool) {
+	if i.hidden == visible {
+		i.hidden = !visible
+		i.version++
+	}
+}
+
+// Color is added to the tinted texture sample, which makes it suitable for
+// flashing an instance towards a solid color.
 func (i *Instance) Color() mgl32.Vec4 {
 	return i.color
 }
 
 func (i *Instance) SetColor(r, g, b, a float32) {
-	i.color[0] = r
-	i.color[1] = g
-	i.color[2] = b
-	i.color[3] = a
-	i.dirty = true
+	if c := (mgl32.Vec4{r, g, b, a}); i.color != c {
+		i.color = c
+		i.version++
+	}
+}
+
+// Tint is multiplied with the texture sample.  The alpha component acts as
+// the instance opacity.
+func (i *Instance) Tint() mgl32.Vec4 {
+	return i.tint
+}
+
+func (i *Instance) SetTint(r, g, b, a float32) {
+	if t := (mgl32.Vec4{r, g, b, a}); i.tint != t {
+		i.tint = t
+		i.markTintChanged()
+	}
+}
+
+func (i *Instance) Opacity() float32 {
+	return i.tint[3]
+}
+
+func (i *Instance) SetOpacity(a float32) {
+	if i.tint[3] != a {
+		i.tint[3] = a
+		i.markTintChanged()
+	}
+}
+
+// FrameKey is the sheet key of the frame the instance was last assigned by
+// a sprite or text list.
+func (i *Instance) FrameKey() string {
+	return i.frameKey
+}
+
+func (i *Instance) SetFrameKey(key string) {
+	i.frameKey = key
+}
+
+// Text is the string last rendered into this instance by a text list.
+func (i *Instance) Text() string {
+	return i.text
+}
+
+// SetTextContent records the text shown by this instance.  It does not
+// render anything; see text.TextInstanceList.SetText.
+func (i *Instance) SetTextContent(text string) {
+	i.text = text
+}
+
+// List returns the list which owns the instance, or nil once removed.
+func (i *Instance) List() Instances {
+	return i.list
 }
 
 func (i *Instance) Next() *Instance {
 	return i.next
 }
 
+// Remove takes the instance out of whichever list owns it.
 func (i *Instance) Remove() {
+	if i.list != nil {
+		i.list.Remove(i)
+	}
+}
+
+// InsertAfter places inst directly after this instance in lists which
+// support ordered insertion.
+func (i *Instance) InsertAfter(inst *Instance) {
+	if list, ok := i.list.(OrderedInstances); ok {
+		list.InsertAfter(i, inst)
+	}
+}
+
+// InsertBefore places inst directly before this instance in lists which
+// support ordered insertion.
+func (i *Instance) InsertBefore(inst *Instance) {
+	if list, ok := i.list.(OrderedInstances); ok {
+		list.InsertBefore(i, inst)
+	}
+}
+
+func (i *Instance) unlink() {
 	if i.next != nil {
 		i.next.prev = i.prev
 	}
@@ -107,10 +316,7 @@ func (i *Instance) Remove() {
 	i.list = nil
 }
 
-func (i *Instance) InsertAfter(inst *Instance) {
-	if inst == nil {
-		return
-	}
+func (i *Instance) linkAfter(inst *Instance) {
 	if i.next != nil {
 		i.next.prev = inst
 	}
@@ -121,5 +327,5 @@ func (i *Instance) InsertAfter(inst *Instance) {
 }
 
 func (i *Instance) MarkChanged() {
-	i.dirty = true
+	i.markDirty()
 }
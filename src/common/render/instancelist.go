@@ -16,22 +16,114 @@ package render
 
 import (
 	"github.com/go-gl/mathgl/mgl32"
+	"github.com/kurrik/opengl-benchmarks/common/core"
 )
 
 type Instances interface {
 	Head() *Instance
+	Len() int
 	Prepend(inst *Instance)
+	Remove(inst *Instance)
 	NewInstance() (inst *Instance)
 }
 
+// OrderedInstances supports inserting relative to an existing member.
+type OrderedInstances interface {
+	Instances
+	InsertAfter(mark, inst *Instance)
+	InsertBefore(mark, inst *Instance)
+}
+
+// BlendedInstances may be implemented by lists which want to be drawn with
+// a specific blend mode regardless of the renderer's setting.
+type BlendedInstances interface {
+	BlendMode() core.BlendMode
+}
+
+// VisibleInstances may be implemented by lists which can be hidden as a
+// whole.
+type VisibleInstances interface {
+	Visible() bool
+}
+
+func listVisible(instances Instances) bool {
+	if list, ok := instances.(VisibleInstances); ok {
+		return list.Visible()
+	}
+	return true
+}
+
+// InstanceIterator walks a list while tolerating removal of the instance it
+// most recently returned.  On linked lists the instance after it may be
+// removed instead, but not both.  Lists with random access are walked by
+// index, re-reading the current position when its instance was removed.
+type InstanceIterator struct {
+	owner   Instances
+	indexed IndexedInstances
+	current *Instance
+	next    *Instance
+	index   int
+}
+
+func NewInstanceIterator(instances Instances) (it *InstanceIterator) {
+	it = &InstanceIterator{}
+	if indexed, ok := instances.(IndexedInstances); ok {
+		it.indexed = indexed
+		return
+	}
+	if it.next = instances.Head(); it.next != nil {
+		// Wrapping lists embed the list which instances report as owner.
+		it.owner = it.next.list
+	}
+	return
+}
+
+func (it *InstanceIterator) Next() (inst *Instance) {
+	if it.indexed != nil {
+		return it.nextIndexed()
+	}
+	if it.current != nil && it.current.list == it.owner {
+		inst = it.current.next
+	} else if it.next != nil && it.next.list == it.owner {
+		inst = it.next
+	}
+	it.current = inst
+	it.next = nil
+	if inst != nil {
+		it.next = inst.next
+	}
+	return
+}
+
+func (it *InstanceIterator) nextIndexed() (inst *Instance) {
+	if it.current != nil {
+		if it.index > it.indexed.Len() || it.indexed.At(it.index-1) != it.current {
+			// Removal moved another instance into the current position.
+			it.index--
+		}
+	}
+	it.current = nil
+	if it.index >= it.indexed.Len() {
+		return
+	}
+	inst = it.indexed.At(it.index)
+	it.current = inst
+	it.index++
+	return
+}
+
 type InstanceList struct {
-	count int
-	root  Instance
+	count     int
+	root      Instance
+	tail      *Instance
+	blendMode core.BlendMode
+	hidden    bool
 }
 
 func NewInstanceList() (l *InstanceList) {
 	l = &InstanceList{}
 	l.root.list = l
+	l.tail = &l.root
 	return
 }
 
@@ -39,11 +131,86 @@ func (l *InstanceList) Head() *Instance {
 	return l.root.next
 }
 
-func (l *InstanceList) Prepend(inst *Instance) {
-	l.root.InsertAfter(inst)
+func (l *InstanceList) Tail() *Instance {
+	if l.tail == &l.root {
+		return nil
+	}
+	return l.tail
+}
+
+func (l *InstanceList) Len() int {
+	return l.count
+}
+
+func (l *InstanceList) Iterate() *InstanceIterator {
+	return NewInstanceIterator(l)
+}
+
+// insertAfter links inst after mark, which must belong to this list (or be
+// the root).  Instances owned by another list are removed from it first.
+// Instances allocated by an InstancePool are ignored, since the pool
+// recycles their storage.
+func (l *InstanceList) insertAfter(mark, inst *Instance) {
+	if inst == nil || inst == mark || inst.pool != nil {
+		return
+	}
+	if inst.list != nil {
+		inst.Remove()
+	}
+	mark.linkAfter(inst)
+	if mark == l.tail {
+		l.tail = inst
+	}
 	l.count++
 }
 
+func (l *InstanceList) Prepend(inst *Instance) {
+	l.insertAfter(&l.root, inst)
+}
+
+func (l *InstanceList) Append(inst *Instance) {
+	l.insertAfter(l.tail, inst)
+}
+
+func (l *InstanceList) InsertAfter(mark, inst *Instance) {
+	if mark == nil || mark.list != l {
+		return
+	}
+	l.insertAfter(mark, inst)
+}
+
+func (l *InstanceList) InsertBefore(mark, inst *Instance) {
+	if mark == nil || mark.list != l {
+		return
+	}
+	l.insertAfter(mark.prev, inst)
+}
+
+func (l *InstanceList) Remove(inst *Instance) {
+	if inst == nil || inst.list != l {
+		return
+	}
+	if inst == l.tail {
+		l.tail = inst.prev
+	}
+	inst.unlink()
+	l.count--
+}
+
+// Clear detaches every instance from the list.
+func (l *InstanceList) Clear() {
+	var inst, next *Instance
+	for inst = l.root.next; inst != nil; inst = next {
+		next = inst.next
+		inst.next = nil
+		inst.prev = nil
+		inst.list = nil
+	}
+	l.root.next = nil
+	l.tail = &l.root
+	l.count = 0
+}
+
 func (l *InstanceList) NewInstance() (inst *Instance) {
 	inst = newInstance()
 	inst.SetPosition(mgl32.Vec3{0, 0, 0})
@@ -53,3 +220,19 @@ func (l *InstanceList) NewInstance() (inst *Instance) {
 	l.Prepend(inst)
 	return
 }
+
+func (l *InstanceList) BlendMode() core.BlendMode {
+	return l.blendMode
+}
+
+func (l *InstanceList) SetBlendMode(mode core.BlendMode) {
+	l.blendMode = mode
+}
+
+func (l *InstanceList) Visible() bool {
+	return !l.hidden
+}
+
+func (l *InstanceList) SetVisible(visible bool) {
+	l.hidden = !visible
+}
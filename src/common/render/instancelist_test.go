@@ -0,0 +1,263 @@
+// Copyright 2016 Arne Roomann-Kurrik
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package render
+
+import (
+	"reflect"
+	"testing"
+)
+
+// numbered creates count instances whose Frame is their creation order.
+func numbered(instances Instances, count int) (all []*Instance) {
+	all = make([]*Instance, count)
+	for i := range all {
+		if list, ok := instances.(*InstanceList); ok {
+			all[i] = newInstance()
+			list.Append(all[i])
+		} else {
+			all[i] = instances.NewInstance()
+		}
+		all[i].Frame = i
+	}
+	return
+}
+
+// frames lists the Frame of each instance reached by walking from Head.
+func frames(instances Instances) (out []int) {
+	out = []int{}
+	for inst := instances.Head(); inst != nil; inst = inst.Next() {
+		out = append(out, inst.Frame)
+	}
+	return
+}
+
+func checkFrames(t *testing.T, instances Instances, want ...int) {
+	t.Helper()
+	if want == nil {
+		want = []int{}
+	}
+	if got := frames(instances); !reflect.DeepEqual(got, want) {
+		t.Errorf("Got order %v, want %v", got, want)
+	}
+	if instances.Len() != len(want) {
+		t.Errorf("Got Len %v, want %v", instances.Len(), len(want))
+	}
+}
+
+func TestInstanceListAppendPrepend(t *testing.T) {
+	var (
+		l = NewInstanceList()
+		a = newInstance()
+		b = newInstance()
+		c = newInstance()
+	)
+	a.Frame, b.Frame, c.Frame = 0, 1, 2
+	checkFrames(t, l)
+	l.Append(b)
+	l.Append(c)
+	l.Prepend(a)
+	checkFrames(t, l, 0, 1, 2)
+	if l.Head() != a || l.Tail() != c {
+		t.Errorf("Got head %v and tail %v", l.Head().Frame, l.Tail().Frame)
+	}
+	if a.List() != l {
+		t.Errorf("Instance not owned by its list")
+	}
+}
+
+func TestInstanceListInsert(t *testing.T) {
+	var (
+		l   = NewInstanceList()
+		all = numbered(l, 3)
+		x   = newInstance()
+		y   = newInstance()
+	)
+	x.Frame, y.Frame = 10, 11
+	l.InsertBefore(all[0], x)
+	checkFrames(t, l, 10, 0, 1, 2)
+	l.InsertBefore(all[2], y)
+	checkFrames(t, l, 10, 0, 1, 11, 2)
+	all[2].InsertAfter(x)
+	checkFrames(t, l, 0, 1, 11, 2, 10)
+	if l.Tail() != x {
+		t.Errorf("Tail not updated by InsertAfter")
+	}
+	l.InsertBefore(newInstance(), y)
+	checkFrames(t, l, 0, 1, 11, 2, 10)
+}
+
+func TestInstanceListRemoveClear(t *testing.T) {
+	var (
+		l   = NewInstanceList()
+		all = numbered(l, 4)
+	)
+	all[3].Remove()
+	checkFrames(t, l, 0, 1, 2)
+	if l.Tail() != all[2] {
+		t.Errorf("Tail not updated by Remove")
+	}
+	all[3].Remove()
+	l.Remove(all[0])
+	checkFrames(t, l, 1, 2)
+	l.Clear()
+	checkFrames(t, l)
+	if l.Tail() != nil || all[1].List() != nil || all[2].Next() != nil {
+		t.Errorf("Clear left instances linked")
+	}
+	l.Append(all[2])
+	checkFrames(t, l, 2)
+}
+
+func TestInstancePoolRemoveClear(t *testing.T) {
+	var (
+		p   = NewInstancePool()
+		all = numbered(p, 4)
+	)
+	checkFrames(t, p, 0, 1, 2, 3)
+	p.Remove(all[1])
+	checkFrames(t, p, 0, 3, 2)
+	p.Clear()
+	checkFrames(t, p)
+}
+
+func iterated(it *InstanceIterator, remove func(inst *Instance)) (out []int) {
+	out = []int{}
+	for inst := it.Next(); inst != nil; inst = it.Next() {
+		out = append(out, inst.Frame)
+		remove(inst)
+	}
+	return
+}
+
+func TestIterateRemoveCurrent(t *testing.T) {
+	var (
+		l = NewInstanceList()
+		p = NewInstancePool()
+	)
+	numbered(l, 5)
+	numbered(p, 5)
+	if got := iterated(l.Iterate(), (*Instance).Remove); !reflect.DeepEqual(got, []int{0, 1, 2, 3, 4}) {
+		t.Errorf("Iterating list got %v", got)
+	}
+	// Each removal moves the pool's last instance into the current slot,
+	// which is visited next.
+	if got := iterated(p.Iterate(), (*Instance).Remove); !reflect.DeepEqual(got, []int{0, 4, 3, 2, 1}) {
+		t.Errorf("Iterating pool got %v", got)
+	}
+	checkFrames(t, l)
+	checkFrames(t, p)
+}
+
+func TestIterateRemoveSome(t *testing.T) {
+	var (
+		p   = NewInstancePool()
+		l   = NewInstanceList()
+		odd = func(inst *Instance) {
+			if inst.Frame%2 == 1 {
+				inst.Remove()
+			}
+		}
+	)
+	numbered(p, 5)
+	numbered(l, 5)
+	if got := iterated(p.Iterate(), odd); !reflect.DeepEqual(got, []int{0, 1, 4, 2, 3}) {
+		t.Errorf("Iterating pool got %v", got)
+	}
+	checkFrames(t, p, 0, 4, 2)
+	if got := iterated(l.Iterate(), odd); !reflect.DeepEqual(got, []int{0, 1, 2, 3, 4}) {
+		t.Errorf("Iterating list got %v", got)
+	}
+	checkFrames(t, l, 0, 2, 4)
+}
+
+func TestIterateRemoveNext(t *testing.T) {
+	var (
+		l   = NewInstanceList()
+		all = numbered(l, 5)
+		got = iterated(l.Iterate(), func(inst *Instance) {
+			if inst.Frame == 1 {
+				all[2].Remove()
+			}
+		})
+	)
+	if want := []int{0, 1, 3, 4}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Got %v, want %v", got, want)
+	}
+}
+
+func TestMoveBetweenLists(t *testing.T) {
+	var (
+		a   = NewInstanceList()
+		b   = NewInstanceList()
+		p   = NewInstancePool()
+		all = numbered(a, 3)
+	)
+	b.Append(all[1])
+	checkFrames(t, a, 0, 2)
+	checkFrames(t, b, 1)
+	if all[1].List() != b {
+		t.Errorf("Moved instance not owned by its new list")
+	}
+	p.Prepend(all[0])
+	checkFrames(t, a, 2)
+	checkFrames(t, p, 0)
+	a.Append(all[0])
+	checkFrames(t, a, 2, 0)
+	checkFrames(t, p)
+}
+
+func TestPooledInstancesStayInPool(t *testing.T) {
+	var (
+		p      = NewInstancePool()
+		other  = NewInstancePool()
+		l      = NewInstanceList()
+		pooled = numbered(p, 2)
+	)
+	numbered(other, 1)
+	l.Append(pooled[0])
+	other.Prepend(pooled[1])
+	checkFrames(t, p, 0, 1)
+	checkFrames(t, l)
+	checkFrames(t, other, 0)
+	if fresh := p.NewInstance(); fresh == pooled[0] || fresh == pooled[1] {
+		t.Errorf("Pool reused the storage of a live instance")
+	}
+	for _, inst := range pooled {
+		if h := other.Handle(inst); other.Get(h) != nil {
+			t.Errorf("Got handle %v from a pool which does not own the instance", h)
+		}
+	}
+}
+
+func TestPoolHandles(t *testing.T) {
+	var (
+		p      = NewInstancePool()
+		inst   = p.NewInstance()
+		handle = p.Handle(inst)
+	)
+	if p.Get(handle) != inst {
+		t.Errorf("Handle did not resolve to its instance")
+	}
+	p.Remove(inst)
+	if p.Get(handle) != nil {
+		t.Errorf("Handle resolved after removal")
+	}
+	if reused := p.NewInstance(); reused != inst || p.Get(handle) != nil {
+		t.Errorf("Stale handle resolved to reused storage")
+	}
+	if h := p.Handle(newInstance()); p.Get(h) != nil {
+		t.Errorf("Got handle %v for an instance the pool does not hold", h)
+	}
+}
@@ -0,0 +1,216 @@
+// Copyright 2016 Arne Roomann-Kurrik
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package render
+
+import (
+	"github.com/kurrik/opengl-benchmarks/common/core"
+)
+
+// Instances are stored in fixed size pages so that pointers stay valid as
+// the pool grows.
+const instancePageSize = 1024
+
+// InstanceHandle identifies a pooled instance.  It goes stale once the
+// instance is removed, even if its storage is reused.
+type InstanceHandle struct {
+	id         int
+	generation uint32
+}
+
+// InstancePool is an Instances implementation which allocates instances in
+// pages and reuses the storage of removed ones, so creating and removing
+// instances does not allocate once the pool has grown.  Live instances are
+// tracked in a dense slice.  Removal is O(1) and moves the last instance
+// into the vacated position, so draw order is not preserved.  New instances
+// are appended rather than prepended.
+//
+// Instances allocated by a pool stay in it; they cannot be moved to another
+// list or pool.  Instances allocated elsewhere may be added with Prepend.
+type InstancePool struct {
+	root        Instance
+	tail        *Instance
+	pages       [][]Instance
+	generations []uint32
+	free        int // First free slot, chained through Instance.index.
+	active      []*Instance
+	blendMode   core.BlendMode
+	hidden      bool
+}
+
+func NewInstancePool() (p *InstancePool) {
+	p = &InstancePool{
+		free: -1,
+	}
+	p.root.list = p
+	p.tail = &p.root
+	return
+}
+
+func (p *InstancePool) Head() *Instance {
+	return p.root.next
+}
+
+func (p *InstancePool) Len() int {
+	return len(p.active)
+}
+
+func (p *InstancePool) At(index int) *Instance {
+	return p.active[index]
+}
+
+func (p *InstancePool) Iterate() *InstanceIterator {
+	return NewInstanceIterator(p)
+}
+
+// Prepend adds an instance allocated elsewhere to the pool.  Despite the
+// name it is added at the end of the draw order.  Instances allocated by a
+// pool are ignored.
+func (p *InstancePool) Prepend(inst *Instance) {
+	if inst == nil || inst.pool != nil {
+		return
+	}
+	if inst.list != nil {
+		inst.Remove()
+	}
+	p.add(inst)
+}
+
+func (p *InstancePool) add(inst *Instance) {
+	inst.index = len(p.active)
+	p.active = append(p.active, inst)
+	p.tail.linkAfter(inst)
+	p.tail = inst
+}
+
+func (p *InstancePool) NewInstance() (inst *Instance) {
+	var id int
+	if p.free >= 0 {
+		id = p.free
+		p.free = p.slot(id).index
+	} else {
+		id = len(p.generations)
+		if id%instancePageSize == 0 {
+			p.pages = append(p.pages, make([]Instance, instancePageSize))
+		}
+		p.generations = append(p.generations, 0)
+	}
+	inst = p.slot(id)
+	inst.reset(id)
+	inst.pool = p
+	p.add(inst)
+	return
+}
+
+func (p *InstancePool) slot(id int) *Instance {
+	return &p.pages[id/instancePageSize][id%instancePageSize]
+}
+
+func (p *InstancePool) Handle(inst *Instance) InstanceHandle {
+	if inst == nil || inst.pool != p || inst.list != p {
+		return InstanceHandle{id: -1}
+	}
+	if inst.id < 0 || inst.id >= len(p.generations) {
+		return InstanceHandle{id: -1}
+	}
+	return InstanceHandle{
+		id:         inst.id,
+		generation: p.generations[inst.id],
+	}
+}
+
+// Get returns the instance for a handle, or nil if it has been removed.
+func (p *InstancePool) Get(handle InstanceHandle) (inst *Instance) {
+	if handle.id < 0 || handle.id >= len(p.generations) {
+		return nil
+	}
+	if p.generations[handle.id] != handle.generation {
+		return nil
+	}
+	inst = p.slot(handle.id)
+	if inst.list != p {
+		return nil
+	}
+	return
+}
+
+func (p *InstancePool) Remove(inst *Instance) {
+	var (
+		last  int
+		moved *Instance
+	)
+	if inst == nil || inst.list != p {
+		return
+	}
+	if inst.pool == p {
+		// Storage is about to be reused, so drop scene graph links to it.
+		inst.detachHierarchy()
+	}
+	last = len(p.active) - 1
+	moved = p.active[last]
+	if moved != inst {
+		// Move the final instance into the removed one's position in both
+		// the dense slice and the linked order.
+		if moved == p.tail {
+			p.tail = moved.prev
+		}
+		moved.unlink()
+		moved.prev = inst.prev
+		moved.next = inst.next
+		moved.list = p
+		inst.prev.next = moved
+		if inst.next != nil {
+			inst.next.prev = moved
+		} else {
+			p.tail = moved
+		}
+		moved.index = inst.index
+		p.active[inst.index] = moved
+		inst.next = nil
+		inst.prev = nil
+		inst.list = nil
+	} else {
+		p.tail = inst.prev
+		inst.unlink()
+	}
+	p.active[last] = nil
+	p.active = p.active[:last]
+	if inst.pool == p {
+		p.generations[inst.id]++
+		inst.index = p.free
+		p.free = inst.id
+	}
+}
+
+func (p *InstancePool) Clear() {
+	for len(p.active) > 0 {
+		p.Remove(p.active[len(p.active)-1])
+	}
+}
+
+func (p *InstancePool) BlendMode() core.BlendMode {
+	return p.blendMode
+}
+
+func (p *InstancePool) SetBlendMode(mode core.BlendMode) {
+	p.blendMode = mode
+}
+
+func (p *InstancePool) Visible() bool {
+	return !p.hidden
+}
+
+func (p *InstancePool) SetVisible(visible bool) {
+	p.hidden = !visible
+}
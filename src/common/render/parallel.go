@@ -0,0 +1,135 @@
+// Copyright 2016 Arne Roomann-Kurrik
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package render
+
+import (
+	"sync"
+)
+
+// Chunks smaller than this aren't worth handing to another goroutine.
+const minParallelChunk = 256
+
+// IndexedInstances supports random access so that the set can be split into
+// chunks and packed concurrently.  Lists which only support walking via
+// Head and Next are gathered into a slice first.
+type IndexedInstances interface {
+	Instances
+	Len() int
+	At(index int) *Instance
+}
+
+// SetWorkers sets how many goroutines compute model matrices and pack
+// instance data during Render.  Values below 2 keep everything on the
+// calling (GL) thread.
+func (r *Renderer) SetWorkers(workers int) {
+	if workers < 1 {
+		workers = 1
+	}
+	r.workers = workers
+}
+
+// gather collects the visible instances into a slice which workers can
+// partition.
+func (r *Renderer) gather(instances Instances) []*Instance {
+	r.gathered = r.gathered[:0]
+	if indexed, ok := instances.(IndexedInstances); ok {
+		for index := 0; index < indexed.Len(); index++ {
+			r.gatherInstance(indexed.At(index))
+		}
+	} else {
+		for instance := instances.Head(); instance != nil; instance = instance.Next() {
+			r.gatherInstance(instance)
+		}
+	}
+	return r.gathered
+}
+
+func (r *Renderer) gatherInstance(instance *Instance) {
+	if !instance.WorldVisible() {
+		return
+	}
+	if instance.parent != nil {
+		// Child transforms read (and cache) their ancestors' transforms,
+		// which may be shared between chunks, so resolve them up front.
+		instance.GetModel()
+	}
+	r.gathered = append(r.gathered, instance)
+}
+
+func (r *Renderer) renderParallel(geometry *Geometry, instances Instances) (err error) {
+	var (
+		gathered = r.gather(instances)
+		count    = len(gathered)
+	)
+	if cap(r.packed) < count {
+		r.packed = make([]renderInstance, count)
+	}
+	r.packed = r.packed[:count]
+	packParallel(r.packed, gathered, r.workers, minParallelChunk)
+	for start := 0; start < count; start += r.bufferSize {
+		var end = start + r.bufferSize
+		if end > count {
+			end = count
+		}
+		if err = r.draw(geometry, r.packed[start:end]); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// packWorkers limits workers so that none is given fewer than minChunk of
+// count instances.  At least one worker is always returned.
+func packWorkers(count, workers, minChunk int) int {
+	if workers*minChunk > count {
+		workers = count / minChunk
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	return workers
+}
+
+// packParallel packs gathered into packed on up to workers goroutines,
+// using fewer when that would leave a goroutine under minChunk instances.
+func packParallel(packed []renderInstance, gathered []*Instance, workers, minChunk int) {
+	var (
+		count = len(gathered)
+		chunk int
+		wg    sync.WaitGroup
+	)
+	workers = packWorkers(count, workers, minChunk)
+	if workers == 1 {
+		for index, instance := range gathered {
+			packed[index].pack(instance)
+		}
+		return
+	}
+	chunk = (count + workers - 1) / workers
+	for start := 0; start < count; start += chunk {
+		var end = start + chunk
+		if end > count {
+			end = count
+		}
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			for index := start; index < end; index++ {
+				packed[index].pack(gathered[index])
+			}
+		}(start, end)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,92 @@
+// Copyright 2016 Arne Roomann-Kurrik
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package render
+
+import (
+	"fmt"
+	"github.com/go-gl/mathgl/mgl32"
+	"testing"
+)
+
+func packInputs(count int) (packed []renderInstance, gathered []*Instance) {
+	packed = make([]renderInstance, count)
+	gathered = make([]*Instance, count)
+	for i := range gathered {
+		gathered[i] = newInstance()
+		gathered[i].SetPosition(mgl32.Vec3{float32(i), 0, 0})
+		gathered[i].SetRotation(float32(i))
+	}
+	return
+}
+
+func TestPackParallelMatchesSerial(t *testing.T) {
+	var (
+		serial, gathered = packInputs(1000)
+		parallel         = make([]renderInstance, len(gathered))
+	)
+	packParallel(serial, gathered, 1, minParallelChunk)
+	packParallel(parallel, gathered, 4, 1)
+	for i := range serial {
+		if serial[i] != parallel[i] {
+			t.Fatalf("Instance %v packed as %v, want %v", i, parallel[i], serial[i])
+		}
+	}
+}
+
+func TestPackWorkers(t *testing.T) {
+	for _, c := range []struct {
+		count, workers, want int
+	}{
+		{0, 4, 1},
+		{minParallelChunk - 1, 4, 1},
+		{minParallelChunk, 4, 1},
+		{minParallelChunk + 1, 4, 1},
+		{2*minParallelChunk - 1, 4, 1},
+		{2 * minParallelChunk, 4, 2},
+		{2*minParallelChunk + 1, 4, 2},
+		{4 * minParallelChunk, 4, 4},
+		{100 * minParallelChunk, 4, 4},
+		{100 * minParallelChunk, 1, 1},
+	} {
+		var workers = packWorkers(c.count, c.workers, minParallelChunk)
+		if workers != c.want {
+			t.Errorf("%v instances on %v workers: got %v workers, want %v", c.count, c.workers, workers, c.want)
+		}
+		if chunk := (c.count + workers - 1) / workers; workers > 1 && chunk < minParallelChunk {
+			t.Errorf("%v instances on %v workers: chunk of %v is under %v", c.count, c.workers, chunk, minParallelChunk)
+		}
+	}
+}
+
+// BenchmarkPackParallel splits the work regardless of minParallelChunk, so
+// the counts at which extra workers start to pay off can be compared with
+// the threshold.  Run with -cpu to vary GOMAXPROCS.  Every instance's model
+// matrix is recomputed each iteration, as it would be for a list which
+// moves every frame.
+func BenchmarkPackParallel(b *testing.B) {
+	for _, count := range []int{64, 128, 256, 512, 1024, 4096, 16384} {
+		var packed, gathered = packInputs(count)
+		for _, w := range []int{1, 2, 4, 8} {
+			b.Run(fmt.Sprintf("count=%v/workers=%v", count, w), func(b *testing.B) {
+				for n := 0; n < b.N; n++ {
+					for _, instance := range gathered {
+						instance.MarkChanged()
+					}
+					packParallel(packed, gathered, w, 1)
+				}
+			})
+		}
+	}
+}
@@ -30,12 +30,25 @@ in vec2 v_TexturePos;
 in vec2 v_TextureMin;
 in vec2 v_TextureDim;
 in vec4 v_BaseColor;
+in vec4 v_TintColor;
 uniform sampler2D u_Texture;
+uniform bool u_Premultiplied;
+uniform bool u_PremultiplyOutput;
 out vec4 v_FragData;
 
 void main() {
   vec2 v_TexturePosition = v_TextureMin + mod(v_TexturePos, v_TextureDim);
-  v_FragData = clamp(texture(u_Texture, v_TexturePosition) + v_BaseColor, 0.0, 1.0);
+  vec4 v_Tint = v_TintColor;
+  vec4 v_Base = v_BaseColor;
+  if (u_Premultiplied) {
+    v_Tint.rgb *= v_Tint.a;
+    v_Base.rgb *= v_Base.a;
+  }
+  vec4 v_Sample = texture(u_Texture, v_TexturePosition) * v_Tint;
+  v_FragData = clamp(v_Sample + v_Base, 0.0, 1.0);
+  if (u_PremultiplyOutput) {
+    v_FragData.rgb *= v_FragData.a;
+  }
 }`
 
 const VERTEX = `#version 150
@@ -55,6 +68,7 @@ in vec2 v_Texture;
 in float f_VertexFrame;
 in float f_InstanceFrame;
 in vec4 v_Color;
+in vec4 v_Tint;
 in mat4 m_Model;
 uniform mat4 m_View;
 uniform mat4 m_Projection;
@@ -62,6 +76,7 @@ out vec2 v_TexturePos;
 out vec2 v_TextureMin;
 out vec2 v_TextureDim;
 out vec4 v_BaseColor;
+out vec4 v_TintColor;
 
 void main() {
   Tile t_Tile = Tiles[int(f_VertexFrame + f_InstanceFrame)];
@@ -69,6 +84,7 @@ void main() {
   v_TextureDim = t_Tile.texture.xy;
   v_TexturePos = v_Texture * v_TextureDim;
   v_BaseColor = v_Color;
+  v_TintColor = v_Tint;
   gl_Position = m_Projection * m_View * m_Model * vec4(v_Position, 1.0);
 }`
 
@@ -76,17 +92,39 @@ type renderInstance struct {
 	model mgl32.Mat4
 	frame float32
 	color mgl32.Vec4
+	tint  mgl32.Vec4
+}
+
+func (i *renderInstance) pack(instance *Instance) {
+	i.frame = float32(instance.Frame)
+	i.model = instance.GetModel()
+	i.color = instance.Color()
+	i.tint = instance.WorldTint()
 }
 
 type Renderer struct {
 	shader      *core.Program
-	vbo         *core.ArrayBuffer
+	vbo         *core.StreamingBuffer
+	boundID     uint32
+	boundOffset int
+	aFrame      *core.VertexAttribute
+	aModel      *core.VertexAttribute
+	aColor      *core.VertexAttribute
+	aTint       *core.VertexAttribute
 	textureData *core.UniformBlock
 	uView       *core.Uniform
 	uProj       *core.Uniform
+	uPremult    *core.Uniform
+	uPremultOut *core.Uniform
 	bufferSize  int
 	buffer      []renderInstance
 	stride      uintptr
+	blend       *core.BlendState
+	blendMode   core.BlendMode
+	retained    map[Instances]*retainedBatch
+	workers     int
+	gathered    []*Instance
+	packed      []renderInstance
 }
 
 func NewRenderer(bufferSize int) (r *Renderer, err error) {
@@ -99,22 +137,29 @@ func NewRenderer(bufferSize int) (r *Renderer, err error) {
 		bufferSize: bufferSize,
 		buffer:     make([]renderInstance, bufferSize),
 		stride:     instanceStride,
+		blend:      core.NewBlendState(),
+		blendMode:  core.BlendAlpha,
+		retained:   map[Instances]*retainedBatch{},
+		workers:    1,
 	}
 	if err = r.shader.Load(VERTEX, FRAGMENT); err != nil {
 		return
 	}
 	r.shader.Bind()
 
-	r.vbo = core.NewArrayBuffer()
-
-	r.shader.Attrib("f_InstanceFrame", instanceStride).Float(unsafe.Offsetof(instance.frame), 1)
-	r.shader.Attrib("m_Model", instanceStride).Mat4(unsafe.Offsetof(instance.model), 1)
-	r.shader.Attrib("v_Color", instanceStride).Vec4(unsafe.Offsetof(instance.color), 1)
+	r.aFrame = r.shader.Attrib("f_InstanceFrame", instanceStride)
+	r.aModel = r.shader.Attrib("m_Model", instanceStride)
+	r.aColor = r.shader.Attrib("v_Color", instanceStride)
+	r.aTint = r.shader.Attrib("v_Tint", instanceStride)
+	r.vbo = core.NewStreamingArrayBuffer(core.UploadSubData, 1)
+	r.registerInstances(r.vbo, 0)
 
 	r.textureData = r.shader.UniformBlock("TextureData", 1)
 
 	r.uView = r.shader.Uniform("m_View")
 	r.uProj = r.shader.Uniform("m_Projection")
+	r.uPremult = r.shader.Uniform("u_Premultiplied")
+	r.uPremultOut = r.shader.Uniform("u_PremultiplyOutput")
 
 	if e := gl.GetError(); e != 0 {
 		err = fmt.Errorf("ERROR: OpenGL error %X", e)
@@ -126,6 +171,60 @@ func (r *Renderer) Bind() {
 	r.shader.Bind()
 }
 
+// SetBlendState shares a blend state tracker (typically Context.Blend) so
+// that redundant mode switches are skipped across renderers.
+func (r *Renderer) SetBlendState(state *core.BlendState) {
+	r.blend = state
+}
+
+// SetBlendMode sets the mode used by subsequent Render calls.  Instance
+// lists which specify their own mode take precedence.
+func (r *Renderer) SetBlendMode(mode core.BlendMode) {
+	r.blendMode = mode
+}
+
+// applyBlend picks the list's mode over the renderer's, switching it to
+// its premultiplied form for premultiplied sheets.  The applied mode is
+// returned.
+func (r *Renderer) applyBlend(instances Instances, premultiplied bool) (mode core.BlendMode) {
+	mode = r.blendMode
+	if blended, ok := instances.(BlendedInstances); ok {
+		if listMode := blended.BlendMode(); listMode != core.BlendDefault {
+			mode = listMode
+		}
+	}
+	r.blend.ApplyPremultiplied(mode, premultiplied)
+	return
+}
+
+// SetUploadStrategy swaps the instance buffer for one which streams data
+// using the given strategy.  Count is the number of buffers (or ring
+// segments) for strategies which rotate through storage.
+func (r *Renderer) SetUploadStrategy(strategy core.UploadStrategy, count int) {
+	if r.vbo != nil {
+		r.vbo.Delete()
+	}
+	r.shader.Bind()
+	r.vbo = core.NewStreamingArrayBuffer(strategy, count)
+	r.registerInstances(r.vbo, r.vbo.Offset())
+}
+
+// registerInstances points the per-instance attributes at offset bytes
+// into buffer.
+func (r *Renderer) registerInstances(buffer core.Buffer, offset int) {
+	var (
+		instance renderInstance
+		base     = uintptr(offset)
+	)
+	buffer.Bind()
+	r.aFrame.Float(base+unsafe.Offsetof(instance.frame), 1)
+	r.aModel.Mat4(base+unsafe.Offsetof(instance.model), 1)
+	r.aColor.Vec4(base+unsafe.Offsetof(instance.color), 1)
+	r.aTint.Vec4(base+unsafe.Offsetof(instance.tint), 1)
+	r.boundID = buffer.BufferID()
+	r.boundOffset = offset
+}
+
 func (r *Renderer) registerGeometry(geometry *Geometry) {
 	var (
 		pt       Point
@@ -155,20 +254,42 @@ func (r *Renderer) Delete() {
 		r.vbo.Delete()
 		r.vbo = nil
 	}
+	for instances, batch := range r.retained {
+		batch.Delete()
+		delete(r.retained, instances)
+	}
 }
 
-func (r *Renderer) draw(geometry *Geometry, count int) (err error) {
+func (r *Renderer) draw(geometry *Geometry, data []renderInstance) (err error) {
+	var count = len(data)
 	if count <= 0 {
 		return
 	}
-	r.vbo.Upload(r.buffer, count*int(r.stride))
-	gl.DrawArraysInstanced(gl.TRIANGLES, 0, int32(len(geometry.Points)), int32(count))
+	r.vbo.Upload(data, count*int(r.stride))
+	if r.vbo.BufferID() != r.boundID || r.vbo.Offset() != r.boundOffset {
+		r.registerInstances(r.vbo, r.vbo.Offset())
+	}
+	r.drawInstanced(geometry, count)
 	if e := gl.GetError(); e != 0 {
 		err = fmt.Errorf("ERROR: OpenGL error %X", e)
 	}
 	return
 }
 
+func (r *Renderer) drawInstanced(geometry *Geometry, count int) {
+	if geometry.Indexed() {
+		gl.DrawElementsInstanced(
+			gl.TRIANGLES,
+			int32(len(geometry.Indices)),
+			gl.UNSIGNED_INT,
+			gl.PtrOffset(0),
+			int32(count),
+		)
+	} else {
+		gl.DrawArraysInstanced(gl.TRIANGLES, 0, int32(len(geometry.Points)), int32(count))
+	}
+}
+
 func (r *Renderer) Render(
 	camera *core.Camera,
 	sheet UniformBufferSheet,
@@ -177,29 +298,124 @@ func (r *Renderer) Render(
 ) (err error) {
 	var (
 		instance *Instance
-		i        *renderInstance
 		index    int
 	)
-	r.uView.Mat4(camera.View)
-	r.uProj.Mat4(camera.Projection)
-	r.registerGeometry(geometry)
-	r.registerTextureData(sheet)
+	if !listVisible(instances) {
+		return
+	}
+	r.prepare(camera, sheet, geometry, instances)
+	if r.workers > 1 {
+		return r.renderParallel(geometry, instances)
+	}
 	index = 0
-	instance = instances.Head()
-	for instance != nil {
-		i = &r.buffer[index]
-		i.frame = float32(instance.Frame)
-		i.model = instance.GetModel()
-		i.color = instance.Color()
-		index++
-		instance = instance.Next()
-		if index >= r.bufferSize {
-			if err = r.draw(geometry, index); err != nil {
+	if indexed, ok := instances.(IndexedInstances); ok {
+		for n := 0; n < indexed.Len(); n++ {
+			if index, err = r.add(geometry, index, indexed.At(n)); err != nil {
+				return
+			}
+		}
+	} else {
+		instance = instances.Head()
+		for instance != nil {
+			if index, err = r.add(geometry, index, instance); err != nil {
 				return
 			}
-			index = 0
+			instance = instance.Next()
 		}
 	}
-	err = r.draw(geometry, index)
+	err = r.draw(geometry, r.buffer[:index])
 	return
 }
+
+// add packs instance at index in the staging buffer, flushing the buffer
+// with a draw call once it is full.
+func (r *Renderer) add(geometry *Geometry, index int, instance *Instance) (next int, err error) {
+	if !instance.WorldVisible() {
+		return index, nil
+	}
+	r.buffer[index].pack(instance)
+	if next = index + 1; next >= r.bufferSize {
+		err = r.draw(geometry, r.buffer[:next])
+		next = 0
+	}
+	return
+}
+
+func (r *Renderer) prepare(
+	camera *core.Camera,
+	sheet UniformBufferSheet,
+	geometry *Geometry,
+	instances Instances,
+) {
+	r.uView.Mat4(camera.View)
+	r.uProj.Mat4(camera.Projection)
+	r.registerGeometry(geometry)
+	r.registerTextureData(sheet)
+	var (
+		premultiplied bool
+		mode          core.BlendMode
+	)
+	if p, ok := sheet.(PremultipliedSheet); ok {
+		premultiplied = p.Premultiplied()
+	}
+	if premultiplied {
+		r.uPremult.Int(1)
+	} else {
+		r.uPremult.Int(0)
+	}
+	mode = r.applyBlend(instances, premultiplied)
+	if mode.NeedsPremultiplied() && !premultiplied {
+		// Straight color from the texture has to be premultiplied for
+		// this mode's blend function.
+		r.uPremultOut.Int(1)
+	} else {
+		r.uPremultOut.Int(0)
+	}
+}
+
+// RenderRetained draws instances from GPU storage which persists between
+// frames.  Only instances which changed since the previous call are
+// uploaded, so mostly static lists cost little more than the draw call.
+// The storage, and a reference to the list, are held until ReleaseRetained
+// is called for the list or the renderer is deleted; call ReleaseRetained
+// when discarding a list or its GPU memory leaks.
+func (r *Renderer) RenderRetained(
+	camera *core.Camera,
+	sheet UniformBufferSheet,
+	geometry *Geometry,
+	instances Instances,
+) (err error) {
+	var (
+		batch  *retainedBatch
+		exists bool
+		count  int
+	)
+	if !listVisible(instances) {
+		return
+	}
+	if batch, exists = r.retained[instances]; !exists {
+		batch = newRetainedBatch()
+		r.retained[instances] = batch
+	}
+	r.prepare(camera, sheet, geometry, instances)
+	if count = batch.sync(instances, int(r.stride)); count == 0 {
+		return
+	}
+	if batch.vbo.BufferID() != r.boundID || r.boundOffset != 0 {
+		r.registerInstances(batch.vbo, 0)
+	}
+	r.drawInstanced(geometry, count)
+	if e := gl.GetError(); e != 0 {
+		err = fmt.Errorf("ERROR: OpenGL error %X", e)
+	}
+	return
+}
+
+// ReleaseRetained frees the GPU storage held for instances by
+// RenderRetained.
+func (r *Renderer) ReleaseRetained(instances Instances) {
+	if batch, exists := r.retained[instances]; exists {
+		batch.Delete()
+		delete(r.retained, instances)
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright 2016 Arne Roomann-Kurrik
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package render
+
+import (
+	"github.com/kurrik/opengl-benchmarks/common/core"
+)
+
+// retainedBatch keeps a GPU copy of an instance list where each instance
+// occupies a stable slot.  Only slots whose instance changed are uploaded.
+type retainedBatch struct {
+	vbo      *core.ArrayBuffer
+	data     []renderInstance
+	slots    []retainedSlot
+	holes    int
+	dirtyMin int
+	dirtyMax int
+}
+
+// retainedSlot records what was last uploaded to a slot.  The version is
+// compared rather than cleared so that several batches (or renderers) can
+// track the same instance independently.  Slots whose instance left the
+// list hold zeroed data, which draws nothing, until the batch compacts.
+type retainedSlot struct {
+	instance *Instance
+	version  uint32
+	visible  bool
+}
+
+func newRetainedBatch() *retainedBatch {
+	return &retainedBatch{
+		vbo: core.NewArrayBuffer(),
+	}
+}
+
+func (b *retainedBatch) markDirty(index int) {
+	if b.dirtyMin >= b.dirtyMax {
+		b.dirtyMin = index
+		b.dirtyMax = index + 1
+		return
+	}
+	if index < b.dirtyMin {
+		b.dirtyMin = index
+	}
+	if index+1 > b.dirtyMax {
+		b.dirtyMax = index + 1
+	}
+}
+
+// vacate zeroes a slot whose instance is no longer in the list.
+func (b *retainedBatch) vacate(index int) {
+	if b.slots[index].instance == nil {
+		return
+	}
+	b.slots[index] = retainedSlot{}
+	b.data[index] = renderInstance{}
+	b.markDirty(index)
+}
+
+// write stores instance in a slot, uploading it if anything differs from
+// what the slot last held.
+func (b *retainedBatch) write(index int, instance *Instance) {
+	var (
+		slot    = &b.slots[index]
+		visible = instance.WorldVisible()
+	)
+	if slot.instance == instance && slot.version == instance.version && slot.visible == visible {
+		if !visible || b.data[index].frame == float32(instance.Frame) {
+			return
+		}
+	}
+	*slot = retainedSlot{
+		instance: instance,
+		version:  instance.version,
+		visible:  visible,
+	}
+	if visible {
+		b.data[index].pack(instance)
+	} else {
+		b.data[index] = renderInstance{}
+	}
+	b.markDirty(index)
+}
+
+// update walks the list in draw order, assigning instances to slots.
+// Removing an instance only vacates its slot, so the instances after it are
+// not uploaded again.  Inserting ahead of existing instances shifts (and so
+// rewrites) everything after the insertion point.  Once vacant slots make
+// up half of the buffer it is rewritten compactly.
+func (b *retainedBatch) update(instances Instances) (count int) {
+	var (
+		instance *Instance
+		owner    Instances
+	)
+	if b.holes > 0 && b.holes*2 >= len(b.slots) {
+		b.slots = b.slots[:0]
+		b.data = b.data[:0]
+	}
+	b.holes = 0
+	if instance = instances.Head(); instance != nil {
+		// Wrapping lists embed the list which instances report as owner.
+		owner = instance.list
+	}
+	for ; instance != nil; instance = instance.Next() {
+		for count < len(b.slots) && b.slots[count].instance != instance {
+			if held := b.slots[count].instance; held != nil && held.list == owner {
+				break
+			}
+			b.vacate(count)
+			b.holes++
+			count++
+		}
+		if count >= len(b.slots) {
+			b.slots = append(b.slots, retainedSlot{})
+			b.data = append(b.data, renderInstance{})
+		}
+		b.write(count, instance)
+		count++
+	}
+	for i := count; i < len(b.slots); i++ {
+		b.slots[i] = retainedSlot{}
+	}
+	b.slots = b.slots[:count]
+	b.data = b.data[:count]
+	return
+}
+
+// sync updates the slots and uploads the range which changed.
+func (b *retainedBatch) sync(instances Instances, stride int) (count int) {
+	if count = b.update(instances); count == 0 {
+		return
+	}
+	if needed := count * stride; needed > b.vbo.Size() {
+		if needed < 2*b.vbo.Size() {
+			needed = 2 * b.vbo.Size()
+		}
+		b.vbo.Reserve(needed)
+		b.dirtyMin = 0
+		b.dirtyMax = count
+	}
+	if b.dirtyMax > count {
+		b.dirtyMax = count
+	}
+	if b.dirtyMin < b.dirtyMax {
+		b.vbo.UploadRange(
+			&b.data[b.dirtyMin],
+			b.dirtyMin*stride,
+			(b.dirtyMax-b.dirtyMin)*stride,
+		)
+	}
+	b.dirtyMin = 0
+	b.dirtyMax = 0
+	return
+}
+
+func (b *retainedBatch) Delete() {
+	if b.vbo != nil {
+		b.vbo.Delete()
+		b.vbo = nil
+	}
+}
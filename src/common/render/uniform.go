@@ -21,6 +21,12 @@ type UniformBufferSheet interface {
 	BufferID() uint32
 }
 
+// PremultipliedSheet is implemented by sheets which know whether their
+// texture stores premultiplied alpha.
+type PremultipliedSheet interface {
+	Premultiplied() bool
+}
+
 type UniformSprite [4]float32
 
 func NewUniformSprite(texW, texH, texX, texY float32) UniformSprite {
@@ -0,0 +1,239 @@
+// Copyright 2016 Arne Roomann-Kurrik
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sprites
+
+import (
+	"fmt"
+	"github.com/kurrik/opengl-benchmarks/common/render"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type AnimationMode int
+
+const (
+	AnimationLoop AnimationMode = iota
+	AnimationPingPong
+	AnimationOnce
+)
+
+type Animation struct {
+	Name      string
+	Frames    []string
+	Durations []time.Duration
+	Mode      AnimationMode
+}
+
+// NewAnimation creates an animation which shows each frame for the same
+// duration.  Durations may be edited afterwards to vary per frame.
+func NewAnimation(name string, frames []string, frameDuration time.Duration, mode AnimationMode) *Animation {
+	var durations = make([]time.Duration, len(frames))
+	for i := range durations {
+		durations[i] = frameDuration
+	}
+	return &Animation{
+		Name:      name,
+		Frames:    frames,
+		Durations: durations,
+		Mode:      mode,
+	}
+}
+
+func (a *Animation) validate() error {
+	if len(a.Frames) == 0 {
+		return fmt.Errorf("Animation %v has no frames", a.Name)
+	}
+	if len(a.Durations) != len(a.Frames) {
+		return fmt.Errorf("Animation %v has %v frames but %v durations", a.Name, len(a.Frames), len(a.Durations))
+	}
+	for i, duration := range a.Durations {
+		if duration <= 0 {
+			return fmt.Errorf("Animation %v frame %v has no duration", a.Name, i)
+		}
+	}
+	return nil
+}
+
+type numberedFrame struct {
+	key    string
+	number int
+}
+
+// AnimationsFromSheet groups keys which end in a number, such as
+// numbered_squares_01 .. numbered_squares_16, into animations named after
+// the shared prefix (numbered_squares).  Groups with a single frame are
+// skipped.
+func AnimationsFromSheet(sheet *Sheet, frameDuration time.Duration, mode AnimationMode) (out map[string]*Animation) {
+	var groups = map[string][]numberedFrame{}
+	for _, key := range sheet.Keys() {
+		var (
+			prefix = strings.TrimRight(key, "0123456789")
+			number int
+			err    error
+		)
+		if prefix == key {
+			continue
+		}
+		if number, err = strconv.Atoi(key[len(prefix):]); err != nil {
+			continue
+		}
+		prefix = strings.TrimRight(prefix, "_- ")
+		groups[prefix] = append(groups[prefix], numberedFrame{key, number})
+	}
+	out = map[string]*Animation{}
+	for name, frames := range groups {
+		if len(frames) < 2 {
+			continue
+		}
+		sort.Slice(frames, func(i, j int) bool {
+			return frames[i].number < frames[j].number
+		})
+		var keys = make([]string, len(frames))
+		for i, frame := range frames {
+			keys[i] = frame.key
+		}
+		out[name] = NewAnimation(name, keys, frameDuration, mode)
+	}
+	return
+}
+
+// AnimationCallback is invoked when a Once animation finishes and each
+// time a looping or ping-pong animation completes a cycle.
+type AnimationCallback func(instance *render.Instance, animation *Animation)
+
+type animationState struct {
+	animation  *Animation
+	frame      int
+	direction  int
+	elapsed    time.Duration
+	onComplete AnimationCallback
+}
+
+// Animator advances sprite animations, updating each instance's frame
+// (and therefore scale) through its sprite list.
+type Animator struct {
+	list    SpriteInstances
+	playing map[*render.Instance]*animationState
+}
+
+func NewAnimator(list SpriteInstances) *Animator {
+	return &Animator{
+		list:    list,
+		playing: map[*render.Instance]*animationState{},
+	}
+}
+
+// Play starts animation on instance from its first frame, replacing any
+// animation already playing there.  onComplete may be nil.
+func (a *Animator) Play(instance *render.Instance, animation *Animation, onComplete AnimationCallback) (err error) {
+	if instance == nil {
+		return // No error
+	}
+	if err = animation.validate(); err != nil {
+		return
+	}
+	if err = a.list.SetFrame(instance, animation.Frames[0]); err != nil {
+		return
+	}
+	a.playing[instance] = &animationState{
+		animation:  animation,
+		frame:      0,
+		direction:  1,
+		onComplete: onComplete,
+	}
+	return
+}
+
+func (a *Animator) Stop(instance *render.Instance) {
+	delete(a.playing, instance)
+}
+
+func (a *Animator) Playing(instance *render.Instance) bool {
+	_, exists := a.playing[instance]
+	return exists
+}
+
+// Update advances every playing animation by elapsed.  Instances which
+// have been removed from their list are dropped.
+func (a *Animator) Update(elapsed time.Duration) (err error) {
+	for instance, state := range a.playing {
+		if instance.List() == nil {
+			delete(a.playing, instance)
+			continue
+		}
+		var (
+			frame    = state.frame
+			replaced bool
+		)
+		state.elapsed += elapsed
+		for state.elapsed >= state.animation.Durations[state.frame] {
+			state.elapsed -= state.animation.Durations[state.frame]
+			var finished = a.advance(instance, state)
+			if a.playing[instance] != state {
+				// The callback stopped the animation or played another.
+				replaced = true
+				break
+			}
+			if finished {
+				delete(a.playing, instance)
+				break
+			}
+		}
+		if !replaced && state.frame != frame {
+			if err = a.list.SetFrame(instance, state.animation.Frames[state.frame]); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+// advance moves to the next frame, returning true once a Once animation
+// has finished.
+func (a *Animator) advance(instance *render.Instance, state *animationState) (finished bool) {
+	var (
+		animation = state.animation
+		last      = len(animation.Frames) - 1
+		completed bool
+	)
+	switch animation.Mode {
+	case AnimationOnce:
+		if state.frame == last {
+			completed = true
+			finished = true
+		} else {
+			state.frame++
+		}
+	case AnimationPingPong:
+		if last == 0 {
+			completed = true
+			break
+		}
+		if state.frame+state.direction < 0 || state.frame+state.direction > last {
+			state.direction = -state.direction
+		}
+		state.frame += state.direction
+		completed = state.frame == 0
+	default:
+		state.frame = (state.frame + 1) % len(animation.Frames)
+		completed = state.frame == 0
+	}
+	if completed && state.onComplete != nil {
+		state.onComplete(instance, animation)
+	}
+	return
+}
@@ -0,0 +1,90 @@
+// Copyright 2016 Arne Roomann-Kurrik
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sprites
+
+import (
+	"github.com/kurrik/opengl-benchmarks/common/render"
+	"testing"
+	"time"
+)
+
+// frameList records frame keys instead of looking them up in a sheet, so
+// no GL context is needed.
+type frameList struct {
+	*render.InstanceList
+}
+
+func (l *frameList) SetFrame(instance *render.Instance, frame string) error {
+	instance.SetFrameKey(frame)
+	return nil
+}
+
+func TestAnimatorChainFromCallback(t *testing.T) {
+	var (
+		list     = &frameList{render.NewInstanceList()}
+		animator = NewAnimator(list)
+		inst     = list.NewInstance()
+		first    = NewAnimation("first", []string{"a1", "a2"}, time.Second, AnimationOnce)
+		second   = NewAnimation("second", []string{"b1", "b2"}, time.Second, AnimationOnce)
+		err      error
+	)
+	if err = animator.Play(inst, first, func(instance *render.Instance, animation *Animation) {
+		if err := animator.Play(instance, second, nil); err != nil {
+			t.Fatal(err)
+		}
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err = animator.Update(2 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if !animator.Playing(inst) {
+		t.Fatalf("Chained animation was dropped")
+	}
+	if inst.FrameKey() != "b1" {
+		t.Errorf("Got frame %v, want b1", inst.FrameKey())
+	}
+	if err = animator.Update(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if inst.FrameKey() != "b2" {
+		t.Errorf("Got frame %v, want b2", inst.FrameKey())
+	}
+}
+
+func TestAnimatorStopFromCallback(t *testing.T) {
+	var (
+		list      = &frameList{render.NewInstanceList()}
+		animator  = NewAnimator(list)
+		inst      = list.NewInstance()
+		loop      = NewAnimation("loop", []string{"a1", "a2"}, time.Second, AnimationLoop)
+		completed int
+	)
+	if err := animator.Play(inst, loop, func(instance *render.Instance, animation *Animation) {
+		completed++
+		animator.Stop(instance)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := animator.Update(5 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if animator.Playing(inst) || completed != 1 {
+		t.Errorf("Got playing %v after %v completions", animator.Playing(inst), completed)
+	}
+	if inst.FrameKey() != "a1" {
+		t.Errorf("Got frame %v, want a1", inst.FrameKey())
+	}
+}
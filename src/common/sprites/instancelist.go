@@ -47,7 +47,8 @@ func (l *SpriteInstanceList) SetFrame(instance *render.Instance, frame string) (
 	}
 	instance.Frame = s.Index()
 	instance.SetScale(s.WorldDimensions(l.pixelsPerUnit).Vec3(1.0))
+	instance.SetDefaultPivot(s.Pivot())
 	instance.MarkChanged()
-	instance.Key = frame
+	instance.SetFrameKey(frame)
 	return
 }
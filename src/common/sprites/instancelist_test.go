@@ -0,0 +1,62 @@
+// Copyright 2016 Arne Roomann-Kurrik
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sprites
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"testing"
+	"time"
+)
+
+func TestSetFramePivot(t *testing.T) {
+	var (
+		sheet = &Sheet{
+			keys:            map[string]*Sprite{},
+			uploadedVersion: -1,
+			ubo:             &recordedBuffer{},
+		}
+		list     = NewSpriteInstanceList(sheet, 32)
+		animator = NewAnimator(list)
+		inst     = list.NewInstance()
+		walk     = NewAnimation("walk", []string{"walk1", "walk2"}, time.Second, AnimationLoop)
+		feet     = mgl32.Vec2{0.5, 0}
+		hips     = mgl32.Vec2{0.5, 0.25}
+		corner   = mgl32.Vec2{0, 0}
+	)
+	sheet.AddSprite("walk1", mgl32.Vec2{32, 32}, mgl32.Vec2{0, 0}).SetPivot(feet)
+	sheet.AddSprite("walk2", mgl32.Vec2{32, 32}, mgl32.Vec2{32, 0}).SetPivot(hips)
+	if err := list.SetFrame(inst, "walk1"); err != nil {
+		t.Fatal(err)
+	}
+	if inst.Pivot() != feet {
+		t.Errorf("Got pivot %v, want the sprite's %v", inst.Pivot(), feet)
+	}
+	if err := list.SetFrame(inst, "walk2"); err != nil {
+		t.Fatal(err)
+	}
+	if inst.Pivot() != hips {
+		t.Errorf("Got pivot %v after changing frame, want %v", inst.Pivot(), hips)
+	}
+	inst.SetPivot(corner)
+	if err := animator.Play(inst, walk, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := animator.Update(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if inst.FrameKey() != "walk2" || inst.Pivot() != corner {
+		t.Errorf("Got frame %v with pivot %v, want walk2 with %v", inst.FrameKey(), inst.Pivot(), corner)
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"github.com/go-gl/mathgl/mgl32"
 	"github.com/golang/glog"
+	"github.com/kurrik/opengl-benchmarks/common/core"
 	"image"
 	"image/draw"
 )
@@ -28,8 +29,13 @@ type PackedSheet struct {
 	Height  int
 	img     draw.Image
 	shelves []*packingShelf
+	dirty   []image.Rectangle // Drawn since the texture was last synced.
 }
 
+// Regions packed near each other are merged into one upload, but more than
+// this many separate regions are merged wherever that wastes the least.
+const maxDirtyRegions = 8
+
 func NewPackedSheet(w, h int) (i *PackedSheet) {
 	return &PackedSheet{
 		Width:   w,
@@ -44,6 +50,56 @@ func (s *PackedSheet) Image() image.Image {
 	return s.img
 }
 
+// Dirty returns the regions packed since the last Sync, which is what the
+// next Sync uploads.
+func (s *PackedSheet) Dirty() []image.Rectangle {
+	return s.dirty
+}
+
+// Sync brings the texture up to date with the packed image.  The first
+// call creates the texture; later calls upload only the dirty regions.
+func (s *PackedSheet) Sync(opts core.TextureOptions) (err error) {
+	var texture *core.Texture
+	if s.texture == nil {
+		if texture, err = core.GetTexture(s.img, opts); err != nil {
+			return
+		}
+		s.SetTexture(texture)
+	} else {
+		for _, region := range s.dirty {
+			s.texture.Update(s.img, region)
+		}
+	}
+	s.dirty = s.dirty[:0]
+	return
+}
+
+func area(r image.Rectangle) int {
+	return r.Dx() * r.Dy()
+}
+
+// addDirty records rect for upload.  It is merged into an existing region
+// when the merged bounds waste no more than rect's own area, as with
+// neighbours on a shelf, or into whichever region grows least once
+// maxDirtyRegions are tracked.
+func addDirty(dirty []image.Rectangle, rect image.Rectangle) []image.Rectangle {
+	var (
+		best   = -1
+		growth int
+	)
+	for i, region := range dirty {
+		var g = area(region.Union(rect)) - area(region) - area(rect)
+		if best == -1 || g < growth {
+			best, growth = i, g
+		}
+	}
+	if best != -1 && (growth <= area(rect) || len(dirty) >= maxDirtyRegions) {
+		dirty[best] = dirty[best].Union(rect)
+		return dirty
+	}
+	return append(dirty, rect)
+}
+
 func (s *PackedSheet) Pack(key string, img image.Image) (err error) {
 	return s.packSprite(key, img, img.Bounds())
 }
@@ -106,5 +162,6 @@ func (s *PackedSheet) packSprite(key string, src image.Image, srcBounds image.Re
 		glog.Infof("packRegion(%v): dest %v src %v", key, destRect, srcBounds.Min)
 	}
 	draw.Draw(s.img, destRect, src, srcBounds.Min, draw.Src)
+	s.dirty = addDirty(s.dirty, destRect)
 	return
 }
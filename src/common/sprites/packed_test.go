@@ -0,0 +1,90 @@
+// Copyright 2016 Arne Roomann-Kurrik
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sprites
+
+import (
+	"image"
+	"reflect"
+	"testing"
+)
+
+func testPackedSheet(w, h int) *PackedSheet {
+	return &PackedSheet{
+		Width:   w,
+		Height:  h,
+		img:     image.NewRGBA(image.Rect(0, 0, w, h)),
+		shelves: []*packingShelf{newShelf()},
+		Sheet: &Sheet{
+			keys:            map[string]*Sprite{},
+			uploadedVersion: -1,
+			ubo:             &recordedBuffer{},
+		},
+	}
+}
+
+func pack(t *testing.T, s *PackedSheet, key string, w, h int) image.Rectangle {
+	t.Helper()
+	if err := s.Pack(key, image.NewRGBA(image.Rect(0, 0, w, h))); err != nil {
+		t.Fatal(err)
+	}
+	sprite, err := s.Sprite(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sprite.ImageBounds()
+}
+
+func TestPackedSheetDirtyNeighbours(t *testing.T) {
+	var (
+		s = testPackedSheet(512, 512)
+		a = pack(t, s, "a", 16, 16)
+		b = pack(t, s, "b", 16, 12)
+	)
+	if want := []image.Rectangle{a.Union(b)}; !reflect.DeepEqual(s.Dirty(), want) {
+		t.Errorf("Got dirty %v, want %v", s.Dirty(), want)
+	}
+}
+
+func TestPackedSheetDirtyApart(t *testing.T) {
+	var s = testPackedSheet(512, 512)
+	pack(t, s, "row", 496, 16)
+	pack(t, s, "rest", 512, 464)
+	s.dirty = s.dirty[:0] // As after Sync.
+	var (
+		a = pack(t, s, "a", 16, 16)
+		b = pack(t, s, "b", 16, 16)
+	)
+	if a != image.Rect(496, 0, 512, 16) || b != image.Rect(0, 480, 16, 496) {
+		t.Fatalf("Packed at %v and %v, want opposite corners", a, b)
+	}
+	if want := []image.Rectangle{a, b}; !reflect.DeepEqual(s.Dirty(), want) {
+		t.Errorf("Got dirty %v, want %v", s.Dirty(), want)
+	}
+}
+
+func TestAddDirtyLimit(t *testing.T) {
+	var dirty []image.Rectangle
+	for i := 0; i < maxDirtyRegions; i++ {
+		dirty = addDirty(dirty, image.Rect(i*64, i*64, i*64+1, i*64+1))
+	}
+	if len(dirty) != maxDirtyRegions {
+		t.Fatalf("Got %v regions, want %v", len(dirty), maxDirtyRegions)
+	}
+	// Closest to the first region, so that is the one which grows.
+	dirty = addDirty(dirty, image.Rect(2, 2, 3, 3))
+	if len(dirty) != maxDirtyRegions || dirty[0] != image.Rect(0, 0, 3, 3) {
+		t.Errorf("Got %v", dirty)
+	}
+}
@@ -22,10 +22,19 @@ import (
 	"unsafe"
 )
 
+// uniformBuffer holds the texture coordinates of every sprite in a sheet.
+type uniformBuffer interface {
+	Upload(data interface{}, size int)
+	BufferID() uint32
+	Size() int
+	Delete()
+}
+
 type Sheet struct {
 	keys            map[string]*Sprite
 	texture         *core.Texture
-	ubo             *core.UniformBuffer
+	borrowed        bool // Texture belongs to someone else, so is never deleted.
+	ubo             uniformBuffer
 	Count           int
 	version         int
 	uploadedVersion int
@@ -40,9 +49,27 @@ func NewSheet() *Sheet {
 	}
 }
 
+// NewTextureSheet wraps texture in a sheet holding a single sprite, key,
+// covering all of it.  Useful for drawing the output of a
+// core.Framebuffer as a sprite.  The texture is borrowed: it still belongs
+// to the caller and is not deleted by SetTexture or Delete.
+func NewTextureSheet(texture *core.Texture, key string) (s *Sheet) {
+	s = NewSheet()
+	s.SetTexture(texture)
+	s.borrowed = true
+	s.AddSprite(key, texture.Size, mgl32.Vec2{0, 0})
+	return
+}
+
+// SetTexture replaces the texture, deleting the previous one unless it
+// was borrowed.  The sheet owns any new texture.  Sprite coordinates are
+// recomputed since the size or orientation may differ.
 func (s *Sheet) SetTexture(texture *core.Texture) {
-	s.deleteTexture()
+	if s.texture != texture {
+		s.deleteTexture()
+	}
 	s.texture = texture
+	s.version++
 }
 
 func (s *Sheet) Bind() {
@@ -59,10 +86,11 @@ func (s *Sheet) Unbind() {
 }
 
 func (s *Sheet) deleteTexture() {
-	if s.texture != nil {
+	if s.texture != nil && !s.borrowed {
 		s.texture.Delete()
-		s.texture = nil
 	}
+	s.texture = nil
+	s.borrowed = false
 }
 
 func (s *Sheet) Delete() {
@@ -80,6 +108,7 @@ func (s *Sheet) AddSprite(key string, bounds, offset mgl32.Vec2) (out *Sprite) {
 		index:  index,
 		bounds: bounds,
 		offset: offset,
+		pivot:  mgl32.Vec2{0.5, 0.5},
 	}
 	s.keys[key] = out
 	s.Count++
@@ -92,6 +121,14 @@ func (s *Sheet) Exists(key string) (exists bool) {
 	return
 }
 
+func (s *Sheet) Keys() (keys []string) {
+	keys = make([]string, 0, len(s.keys))
+	for key := range s.keys {
+		keys = append(keys, key)
+	}
+	return
+}
+
 func (s *Sheet) Sprite(key string) (out *Sprite, err error) {
 	var exists bool
 	if out, exists = s.keys[key]; !exists {
@@ -116,13 +153,17 @@ func (s *Sheet) upload() (err error) {
 		return
 	}
 	for _, sprite = range s.keys {
-		data[sprite.index] = sprite.textureBounds(s.texture.Size)
+		data[sprite.index] = sprite.textureBounds(s.texture.Size, s.texture.BottomUp())
 	}
 	s.ubo.Upload(data, size)
 	s.uploadedVersion = s.version
 	return
 }
 
+func (s *Sheet) Premultiplied() bool {
+	return s.texture != nil && s.texture.Premultiplied()
+}
+
 func (s *Sheet) Texture() *core.Texture {
 	return s.texture
 }
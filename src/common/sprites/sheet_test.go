@@ -0,0 +1,104 @@
+// Copyright 2016 Arne Roomann-Kurrik
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sprites
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/kurrik/opengl-benchmarks/common/core"
+	"github.com/kurrik/opengl-benchmarks/common/render"
+	"testing"
+)
+
+// recordedBuffer keeps uploaded sprite coordinates instead of sending them
+// to GL.
+type recordedBuffer struct {
+	data []render.UniformSprite
+}
+
+func (b *recordedBuffer) Upload(data interface{}, size int) {
+	b.data = data.([]render.UniformSprite)
+}
+
+func (b *recordedBuffer) BufferID() uint32 {
+	return 0
+}
+
+func (b *recordedBuffer) Size() int {
+	return len(b.data) * 16
+}
+
+func (b *recordedBuffer) Delete() {
+}
+
+// uploadedSheet uploads a sheet for a 96x48 image with a sprite covering
+// the whole image and a smaller one inside it.
+func uploadedSheet(t *testing.T, size mgl32.Vec2) (whole, part render.UniformSprite) {
+	t.Helper()
+	var (
+		buffer = &recordedBuffer{}
+		sheet  = &Sheet{
+			keys:            map[string]*Sprite{},
+			uploadedVersion: -1,
+			ubo:             buffer,
+		}
+		a = sheet.AddSprite("whole", mgl32.Vec2{96, 48}, mgl32.Vec2{0, 0})
+		b = sheet.AddSprite("part", mgl32.Vec2{24, 12}, mgl32.Vec2{48, 25})
+	)
+	sheet.SetTexture(&core.Texture{Size: size, OriginalSize: mgl32.Vec2{96, 48}})
+	if err := sheet.upload(); err != nil {
+		t.Fatal(err)
+	}
+	if len(buffer.data) != 2 {
+		t.Fatalf("Uploaded %v sprites, want 2", len(buffer.data))
+	}
+	return buffer.data[a.Index()], buffer.data[b.Index()]
+}
+
+func nearSprite(a, b render.UniformSprite) bool {
+	for i := range a {
+		if !mgl32.FloatEqualThreshold(a[i], b[i], 1e-6) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSheetUploadNPOT(t *testing.T) {
+	for _, c := range []struct {
+		name        string
+		size        mgl32.Vec2
+		whole, part render.UniformSprite
+	}{
+		// Uploaded as is, so coordinates span the whole texture.  Rows are
+		// top first, so each origin is the sprite's bottom row and the
+		// height is negated.
+		{"unpadded", mgl32.Vec2{96, 48},
+			render.UniformSprite{1, -1, 0, 1},
+			render.UniformSprite{0.25, -0.25, 0.5, 37.0 / 48}},
+		// Padded to 128x64 with the image at the top left, so coordinates
+		// are measured against the padded size.
+		{"padded", mgl32.Vec2{128, 64},
+			render.UniformSprite{0.75, -0.75, 0, 0.75},
+			render.UniformSprite{0.1875, -0.1875, 0.375, 0.578125}},
+	} {
+		whole, part := uploadedSheet(t, c.size)
+		if !nearSprite(whole, c.whole) {
+			t.Errorf("%v: whole image got %v, want %v", c.name, whole, c.whole)
+		}
+		if !nearSprite(part, c.part) {
+			t.Errorf("%v: part got %v, want %v", c.name, part, c.part)
+		}
+	}
+}
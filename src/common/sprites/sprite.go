@@ -24,12 +24,23 @@ type Sprite struct {
 	index  int
 	bounds mgl32.Vec2
 	offset mgl32.Vec2
+	pivot  mgl32.Vec2
 }
 
 func (s *Sprite) Index() int {
 	return s.index
 }
 
+// Pivot is the default anchor for instances showing this sprite, in unit
+// coordinates with (0,0) at the bottom left.
+func (s *Sprite) Pivot() mgl32.Vec2 {
+	return s.pivot
+}
+
+func (s *Sprite) SetPivot(pivot mgl32.Vec2) {
+	s.pivot = pivot
+}
+
 func (s *Sprite) ImageBounds() image.Rectangle {
 	return image.Rectangle{
 		image.Point{int(s.offset.X()), int(s.offset.Y())},
@@ -37,12 +48,26 @@ func (s *Sprite) ImageBounds() image.Rectangle {
 	}
 }
 
-func (s *Sprite) textureBounds(textureBounds mgl32.Vec2) render.UniformSprite {
+// textureBounds maps the quad's texture coordinates onto this sprite in a
+// texture of the given size, see core.Texture.Size.  Textures made from
+// images are stored top row first, so the picture is upside down in texture
+// space; the origin is placed at the sprite's bottom row and the height is
+// negated to flip it back.  Rendered (bottomUp) textures are already the
+// right way up.
+func (s *Sprite) textureBounds(size mgl32.Vec2, bottomUp bool) render.UniformSprite {
+	if bottomUp {
+		return render.NewUniformSprite(
+			s.bounds.X()/size.X(),
+			s.bounds.Y()/size.Y(),
+			s.offset.X()/size.X(),
+			1.0-(s.offset.Y()+s.bounds.Y())/size.Y(),
+		)
+	}
 	return render.NewUniformSprite(
-		s.bounds.X()/textureBounds.X(),
-		s.bounds.Y()/textureBounds.Y(),
-		s.offset.X()/textureBounds.X(),
-		1.0-(s.offset.Y()+s.bounds.Y()-1.0)/textureBounds.Y(),
+		s.bounds.X()/size.X(),
+		-s.bounds.Y()/size.Y(),
+		s.offset.X()/size.X(),
+		(s.offset.Y()+s.bounds.Y())/size.Y(),
 	)
 }
 
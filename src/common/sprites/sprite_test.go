@@ -0,0 +1,60 @@
+// Copyright 2016 Arne Roomann-Kurrik
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sprites
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/kurrik/opengl-benchmarks/common/render"
+	"testing"
+)
+
+// Sizes and offsets are chosen so that every expected coordinate is exact
+// in float32.
+func TestSpriteTextureBounds(t *testing.T) {
+	var sprite = &Sprite{
+		bounds: mgl32.Vec2{24, 12},
+		offset: mgl32.Vec2{48, 24},
+	}
+	for _, c := range []struct {
+		name     string
+		size     mgl32.Vec2
+		bottomUp bool
+		want     render.UniformSprite
+	}{
+		// A 96x48 image uploaded as is.  The origin is the sprite's bottom
+		// row, 36 of 48 rows down, and the height is negated.
+		{"unpadded", mgl32.Vec2{96, 48}, false, render.UniformSprite{0.25, -0.25, 0.5, 0.75}},
+		// The same image padded to 128x64 with the picture at the top left.
+		{"padded", mgl32.Vec2{128, 64}, false, render.UniformSprite{0.1875, -0.1875, 0.375, 0.5625}},
+		// Rendered textures store the bottom row first, so the origin is
+		// the sprite's bottom row measured from the bottom.
+		{"bottom up", mgl32.Vec2{96, 48}, true, render.UniformSprite{0.25, 0.25, 0.5, 0.25}},
+		{"bottom up pow2", mgl32.Vec2{128, 64}, true, render.UniformSprite{0.1875, 0.1875, 0.375, 0.4375}},
+	} {
+		if got := sprite.textureBounds(c.size, c.bottomUp); got != c.want {
+			t.Errorf("%v: got %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestSpriteTextureBoundsWholeTexture(t *testing.T) {
+	var sprite = &Sprite{bounds: mgl32.Vec2{96, 48}}
+	if got, want := sprite.textureBounds(mgl32.Vec2{96, 48}, false), (render.UniformSprite{1, -1, 0, 1}); got != want {
+		t.Errorf("Top row first: got %v, want %v", got, want)
+	}
+	if got, want := sprite.textureBounds(mgl32.Vec2{96, 48}, true), (render.UniformSprite{1, 1, 0, 0}); got != want {
+		t.Errorf("Bottom row first: got %v, want %v", got, want)
+	}
+}
@@ -15,7 +15,6 @@
 package text
 
 import (
-	"github.com/go-gl/gl/v3.3-core/gl"
 	"github.com/golang/freetype"
 	"github.com/golang/freetype/truetype"
 	"github.com/kurrik/opengl-benchmarks/common/core"
@@ -103,6 +102,6 @@ func (ff *FontFace) GetText(text string) (t *core.Texture, err error) {
 	if img, err = ff.GetImage(text); err != nil {
 		return
 	}
-	t, err = core.GetTexture(img, gl.NEAREST)
+	t, err = core.GetTexture(img, core.TextureOptions{Smoothing: core.SmoothingNearest})
 	return
 }
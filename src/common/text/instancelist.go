@@ -71,25 +71,19 @@ func (l *TextInstanceList) SetText(instance *render.Instance, text string, font
 	instance.Frame = sprite.Index()
 	instance.SetScale(sprite.WorldDimensions(l.cfg.PixelsPerUnit).Vec3(1.0))
 	instance.MarkChanged()
-	instance.Key = text
-	if err = l.generateTexture(); err != nil {
-		return
-	}
+	instance.SetFrameKey(text)
+	instance.SetTextContent(text)
 	return
 }
 
-func (l *TextInstanceList) generateTexture() (err error) {
-	var (
-		texture *core.Texture
-	)
-	if texture, err = core.GetTexture(
-		l.sheet.Image(),
-		core.SmoothingLinear,
-	); err != nil {
-		return
-	}
-	l.sheet.SetTexture(texture)
-	return
+// Sync uploads text packed since the previous call.  Bind calls it, so
+// any number of SetText calls between frames cost a single upload.  The
+// sheet is premultiplied so linear filtering doesn't darken glyph edges.
+func (l *TextInstanceList) Sync() error {
+	return l.sheet.Sync(core.TextureOptions{
+		Smoothing:   core.SmoothingLinear,
+		Premultiply: true,
+	})
 }
 
 func (l *TextInstanceList) repackImage() (err error) {
@@ -107,20 +101,18 @@ func (l *TextInstanceList) repackImage() (err error) {
 	)
 	instance = l.Head()
 	for instance != nil {
-		if err = newImage.Copy(instance.Key, l.sheet); err != nil {
+		if err = newImage.Copy(instance.FrameKey(), l.sheet); err != nil {
 			return
 		}
-		if sprite, err = newImage.Sheet.Sprite(instance.Key); err != nil {
+		if sprite, err = newImage.Sheet.Sprite(instance.FrameKey()); err != nil {
 			return
 		}
 		instance.Frame = sprite.Index()
 		instance.MarkChanged()
 		instance = instance.Next()
 	}
+	l.sheet.Delete()
 	l.sheet = newImage
-	if err = l.generateTexture(); err != nil {
-		return
-	}
 	if glog.V(1) {
 		glog.Info("Done repacking")
 	}
@@ -128,6 +120,9 @@ func (l *TextInstanceList) repackImage() (err error) {
 }
 
 func (l *TextInstanceList) Bind() {
+	if err := l.Sync(); err != nil {
+		glog.Errorf("Could not sync text texture: %v", err)
+	}
 	l.sheet.Bind()
 }
 
@@ -0,0 +1,120 @@
+// Copyright 2016 Arne Roomann-Kurrik
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tween
+
+import (
+	"math"
+)
+
+// Easing maps linear progress in [0,1] to eased progress.  The result
+// should start at 0 and end at 1 but may overshoot in between.
+type Easing func(t float32) float32
+
+func Linear(t float32) float32 {
+	return t
+}
+
+func QuadIn(t float32) float32 {
+	return t * t
+}
+
+func QuadOut(t float32) float32 {
+	return t * (2 - t)
+}
+
+func QuadInOut(t float32) float32 {
+	if t < 0.5 {
+		return 2 * t * t
+	}
+	return -1 + (4-2*t)*t
+}
+
+func CubicIn(t float32) float32 {
+	return t * t * t
+}
+
+func CubicOut(t float32) float32 {
+	t = t - 1
+	return t*t*t + 1
+}
+
+func CubicInOut(t float32) float32 {
+	if t < 0.5 {
+		return 4 * t * t * t
+	}
+	t = 2*t - 2
+	return 0.5*t*t*t + 1
+}
+
+func SineIn(t float32) float32 {
+	return 1 - float32(math.Cos(float64(t)*math.Pi/2))
+}
+
+func SineOut(t float32) float32 {
+	return float32(math.Sin(float64(t) * math.Pi / 2))
+}
+
+func SineInOut(t float32) float32 {
+	return 0.5 * (1 - float32(math.Cos(float64(t)*math.Pi)))
+}
+
+func ExpoIn(t float32) float32 {
+	if t == 0 {
+		return 0
+	}
+	return float32(math.Pow(2, 10*float64(t-1)))
+}
+
+func ExpoOut(t float32) float32 {
+	if t == 1 {
+		return 1
+	}
+	return 1 - float32(math.Pow(2, -10*float64(t)))
+}
+
+const backOvershoot = 1.70158
+
+func BackIn(t float32) float32 {
+	return t * t * ((backOvershoot+1)*t - backOvershoot)
+}
+
+func BackOut(t float32) float32 {
+	t = t - 1
+	return t*t*((backOvershoot+1)*t+backOvershoot) + 1
+}
+
+func ElasticOut(t float32) float32 {
+	if t == 0 || t == 1 {
+		return t
+	}
+	var p = 0.3
+	return float32(math.Pow(2, -10*float64(t))*math.Sin((float64(t)-p/4)*(2*math.Pi)/p)) + 1
+}
+
+func BounceOut(t float32) float32 {
+	switch {
+	case t < 1/2.75:
+		return 7.5625 * t * t
+	case t < 2/2.75:
+		t -= 1.5 / 2.75
+		return 7.5625*t*t + 0.75
+	case t < 2.5/2.75:
+		t -= 2.25 / 2.75
+		return 7.5625*t*t + 0.9375
+	default:
+		t -= 2.625 / 2.75
+		return 7.5625*t*t + 0.984375
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2016 Arne Roomann-Kurrik
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tween
+
+import (
+	"time"
+)
+
+// Running is returned by Player.Start and allows cancelling an action.
+type Running struct {
+	action    Action
+	cancelled bool
+	done      bool
+}
+
+// Cancel stops the action where it is; values already applied are kept.
+func (r *Running) Cancel() {
+	r.cancelled = true
+}
+
+func (r *Running) Done() bool {
+	return r.done || r.cancelled
+}
+
+// Player advances running actions by frame deltas.  Tweens only call
+// instance setters, which mark an instance changed only when a value
+// actually moves, so instances which aren't being tweened stay clean.
+type Player struct {
+	running  []*Running
+	updating []*Running
+}
+
+func NewPlayer() *Player {
+	return &Player{}
+}
+
+func (p *Player) Start(action Action) (r *Running) {
+	r = &Running{action: action}
+	p.running = append(p.running, r)
+	return
+}
+
+func (p *Player) Len() int {
+	return len(p.running)
+}
+
+func (p *Player) CancelAll() {
+	for _, r := range p.updating {
+		r.Cancel()
+	}
+	for _, r := range p.running {
+		r.Cancel()
+	}
+	p.running = p.running[:0]
+}
+
+func (p *Player) Update(elapsed time.Duration) {
+	var kept = p.running[:0]
+	// Actions started from callbacks during this update are appended to
+	// p.running and will first advance next update.
+	p.updating = p.running
+	p.running = nil
+	for _, r := range p.updating {
+		if !r.cancelled {
+			_, r.done = r.action.Update(elapsed)
+		}
+	}
+	// Filter afterwards, since callbacks may cancel actions which have
+	// already been advanced.
+	for _, r := range p.updating {
+		if !r.Done() {
+			kept = append(kept, r)
+		}
+	}
+	p.updating = nil
+	p.running = append(kept, p.running...)
+}
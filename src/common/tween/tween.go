@@ -0,0 +1,280 @@
+// Copyright 2016 Arne Roomann-Kurrik
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tween
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/kurrik/opengl-benchmarks/common/render"
+	"time"
+)
+
+// Action is anything a Player can advance.  Update consumes up to elapsed
+// and reports how much time was left over once the action finished, so
+// that sequences don't drift.
+type Action interface {
+	Update(elapsed time.Duration) (leftover time.Duration, done bool)
+	Reset()
+}
+
+// Tween interpolates a value over a duration.  Start values are captured
+// the first time the tween runs, so tweens placed later in a sequence begin
+// from wherever earlier ones left off.  Reset replays from those same
+// start values.
+type Tween struct {
+	duration time.Duration
+	elapsed  time.Duration
+	easing   Easing
+	start    func()
+	apply    func(t float32)
+	started  bool
+}
+
+// Func creates a tween which calls apply with eased progress in [0,1].
+// Either start or apply may be nil.
+func Func(duration time.Duration, easing Easing, start func(), apply func(t float32)) *Tween {
+	if easing == nil {
+		easing = Linear
+	}
+	return &Tween{
+		duration: duration,
+		easing:   easing,
+		start:    start,
+		apply:    apply,
+	}
+}
+
+func Delay(duration time.Duration) *Tween {
+	return Func(duration, Linear, nil, nil)
+}
+
+func (t *Tween) Update(elapsed time.Duration) (leftover time.Duration, done bool) {
+	if !t.started {
+		if t.start != nil {
+			t.start()
+		}
+		t.started = true
+	}
+	t.elapsed += elapsed
+	if t.elapsed >= t.duration {
+		leftover = t.elapsed - t.duration
+		t.elapsed = t.duration
+		done = true
+	}
+	if t.apply != nil {
+		if done {
+			t.apply(1)
+		} else {
+			t.apply(t.easing(float32(t.elapsed) / float32(t.duration)))
+		}
+	}
+	return
+}
+
+func (t *Tween) Reset() {
+	t.elapsed = 0
+}
+
+func lerp(from, to, t float32) float32 {
+	return from + (to-from)*t
+}
+
+func lerpVec3(from, to mgl32.Vec3, t float32) mgl32.Vec3 {
+	return from.Add(to.Sub(from).Mul(t))
+}
+
+func lerpVec4(from, to mgl32.Vec4, t float32) mgl32.Vec4 {
+	return from.Add(to.Sub(from).Mul(t))
+}
+
+func Position(inst *render.Instance, to mgl32.Vec3, duration time.Duration, easing Easing) *Tween {
+	var from mgl32.Vec3
+	return Func(duration, easing, func() {
+		from = inst.Position()
+	}, func(t float32) {
+		inst.SetPosition(lerpVec3(from, to, t))
+	})
+}
+
+func Scale(inst *render.Instance, to mgl32.Vec3, duration time.Duration, easing Easing) *Tween {
+	var from mgl32.Vec3
+	return Func(duration, easing, func() {
+		from = inst.Scale()
+	}, func(t float32) {
+		inst.SetScale(lerpVec3(from, to, t))
+	})
+}
+
+// Rotation tweens to an absolute rotation in degrees.
+func Rotation(inst *render.Instance, to float32, duration time.Duration, easing Easing) *Tween {
+	var from float32
+	return Func(duration, easing, func() {
+		from = inst.Rotation()
+	}, func(t float32) {
+		inst.SetRotation(lerp(from, to, t))
+	})
+}
+
+// Color tweens the additive color used for flashes.
+func Color(inst *render.Instance, to mgl32.Vec4, duration time.Duration, easing Easing) *Tween {
+	var from mgl32.Vec4
+	return Func(duration, easing, func() {
+		from = inst.Color()
+	}, func(t float32) {
+		c := lerpVec4(from, to, t)
+		inst.SetColor(c[0], c[1], c[2], c[3])
+	})
+}
+
+func Tint(inst *render.Instance, to mgl32.Vec4, duration time.Duration, easing Easing) *Tween {
+	var from mgl32.Vec4
+	return Func(duration, easing, func() {
+		from = inst.Tint()
+	}, func(t float32) {
+		c := lerpVec4(from, to, t)
+		inst.SetTint(c[0], c[1], c[2], c[3])
+	})
+}
+
+func Opacity(inst *render.Instance, to float32, duration time.Duration, easing Easing) *Tween {
+	var from float32
+	return Func(duration, easing, func() {
+		from = inst.Opacity()
+	}, func(t float32) {
+		inst.SetOpacity(lerp(from, to, t))
+	})
+}
+
+type callback struct {
+	fn func()
+}
+
+// Callback creates an instantaneous action, useful at the end of a
+// sequence.
+func Callback(fn func()) Action {
+	return &callback{fn: fn}
+}
+
+func (c *callback) Update(elapsed time.Duration) (time.Duration, bool) {
+	c.fn()
+	return elapsed, true
+}
+
+func (c *callback) Reset() {
+}
+
+type sequence struct {
+	actions []Action
+	index   int
+}
+
+// Sequence runs actions one after another.
+func Sequence(actions ...Action) Action {
+	return &sequence{actions: actions}
+}
+
+func (s *sequence) Update(elapsed time.Duration) (leftover time.Duration, done bool) {
+	leftover = elapsed
+	for s.index < len(s.actions) {
+		if leftover, done = s.actions[s.index].Update(leftover); !done {
+			return
+		}
+		s.index++
+	}
+	return leftover, true
+}
+
+func (s *sequence) Reset() {
+	for _, action := range s.actions {
+		action.Reset()
+	}
+	s.index = 0
+}
+
+type parallel struct {
+	actions []Action
+	done    []bool
+}
+
+// Parallel runs actions together and finishes when the longest does.
+func Parallel(actions ...Action) Action {
+	return &parallel{
+		actions: actions,
+		done:    make([]bool, len(actions)),
+	}
+}
+
+func (p *parallel) Update(elapsed time.Duration) (leftover time.Duration, done bool) {
+	leftover = elapsed
+	done = true
+	for i, action := range p.actions {
+		if p.done[i] {
+			continue
+		}
+		var remaining time.Duration
+		if remaining, p.done[i] = action.Update(elapsed); !p.done[i] {
+			done = false
+		} else if remaining < leftover {
+			leftover = remaining
+		}
+	}
+	if !done {
+		leftover = 0
+	}
+	return
+}
+
+func (p *parallel) Reset() {
+	for i, action := range p.actions {
+		action.Reset()
+		p.done[i] = false
+	}
+}
+
+type repeat struct {
+	action Action
+	times  int
+	count  int
+}
+
+// Repeat runs action the given number of times, or forever if times is
+// negative.
+func Repeat(action Action, times int) Action {
+	return &repeat{
+		action: action,
+		times:  times,
+	}
+}
+
+func (r *repeat) Update(elapsed time.Duration) (leftover time.Duration, done bool) {
+	leftover = elapsed
+	for r.times < 0 || r.count < r.times {
+		var before = leftover
+		if leftover, done = r.action.Update(leftover); !done {
+			return
+		}
+		r.count++
+		r.action.Reset()
+		if leftover == before && r.times < 0 {
+			// Zero length action; stop spinning until more time passes.
+			return 0, false
+		}
+	}
+	return leftover, true
+}
+
+func (r *repeat) Reset() {
+	r.action.Reset()
+	r.count = 0
+}
@@ -0,0 +1,221 @@
+// Copyright 2016 Arne Roomann-Kurrik
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tween
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"testing"
+	"time"
+)
+
+// progress is a linear tween which remembers the last progress applied.
+func progress(duration time.Duration, last *float32) *Tween {
+	*last = -1
+	return Func(duration, Linear, nil, func(t float32) {
+		*last = t
+	})
+}
+
+// counted runs a delay and then counts how often it finished.
+func counted(duration time.Duration, count *int) Action {
+	return Sequence(Delay(duration), Callback(func() {
+		*count++
+	}))
+}
+
+func TestEasingEndpoints(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		easing Easing
+	}{
+		{"Linear", Linear},
+		{"QuadIn", QuadIn},
+		{"QuadOut", QuadOut},
+		{"QuadInOut", QuadInOut},
+		{"CubicIn", CubicIn},
+		{"CubicOut", CubicOut},
+		{"CubicInOut", CubicInOut},
+		{"SineIn", SineIn},
+		{"SineOut", SineOut},
+		{"SineInOut", SineInOut},
+		{"ExpoIn", ExpoIn},
+		{"ExpoOut", ExpoOut},
+		{"BackIn", BackIn},
+		{"BackOut", BackOut},
+		{"ElasticOut", ElasticOut},
+		{"BounceOut", BounceOut},
+	} {
+		if got := c.easing(0); !mgl32.FloatEqualThreshold(got, 0, 1e-6) {
+			t.Errorf("%v(0) = %v, want 0", c.name, got)
+		}
+		if got := c.easing(1); !mgl32.FloatEqualThreshold(got, 1, 1e-6) {
+			t.Errorf("%v(1) = %v, want 1", c.name, got)
+		}
+	}
+}
+
+func TestTweenZeroDuration(t *testing.T) {
+	var (
+		last  float32
+		tween = progress(0, &last)
+	)
+	if leftover, done := tween.Update(0); !done || leftover != 0 || last != 1 {
+		t.Errorf("Got leftover %v, done %v, progress %v", leftover, done, last)
+	}
+	tween.Reset()
+	if leftover, done := tween.Update(time.Second); !done || leftover != time.Second {
+		t.Errorf("Got leftover %v, done %v after reset", leftover, done)
+	}
+}
+
+func TestSequenceCarriesLeftover(t *testing.T) {
+	var (
+		first, second float32
+		skipped       float32
+		seq           = Sequence(
+			progress(time.Second, &first),
+			progress(0, &skipped),
+			progress(time.Second, &second),
+		)
+	)
+	if _, done := seq.Update(1500 * time.Millisecond); done {
+		t.Fatalf("Sequence finished early")
+	}
+	if first != 1 || skipped != 1 || second != 0.5 {
+		t.Errorf("Got progress %v, %v, %v, want 1, 1, 0.5", first, skipped, second)
+	}
+	if leftover, done := seq.Update(750 * time.Millisecond); !done || leftover != 250*time.Millisecond {
+		t.Errorf("Got leftover %v, done %v, want 250ms, true", leftover, done)
+	}
+}
+
+func TestParallelCarriesLeftover(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		steps    []time.Duration
+		leftover time.Duration
+		done     bool
+	}{
+		{"one step", []time.Duration{2500 * time.Millisecond}, 500 * time.Millisecond, true},
+		{"shorter done first", []time.Duration{1500 * time.Millisecond, time.Second}, 500 * time.Millisecond, true},
+		{"exact", []time.Duration{time.Second, time.Second}, 0, true},
+		{"unfinished", []time.Duration{1500 * time.Millisecond}, 0, false},
+	} {
+		var (
+			short, long float32
+			par         = Parallel(progress(time.Second, &short), progress(2*time.Second, &long))
+			leftover    time.Duration
+			done        bool
+		)
+		for _, step := range c.steps {
+			leftover, done = par.Update(step)
+		}
+		if leftover != c.leftover || done != c.done {
+			t.Errorf("%v: got leftover %v, done %v, want %v, %v", c.name, leftover, done, c.leftover, c.done)
+		}
+		if short != 1 {
+			t.Errorf("%v: shorter tween at %v, want 1", c.name, short)
+		}
+	}
+}
+
+func TestRepeat(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		times    int
+		steps    []time.Duration
+		count    int
+		leftover time.Duration
+		done     bool
+	}{
+		{"never", 0, []time.Duration{time.Second}, 0, time.Second, true},
+		{"partway", 3, []time.Duration{2500 * time.Millisecond}, 2, 0, false},
+		{"finished", 3, []time.Duration{2500 * time.Millisecond, time.Second}, 3, 500 * time.Millisecond, true},
+		{"all at once", 3, []time.Duration{10 * time.Second}, 3, 7 * time.Second, true},
+		{"forever", -1, []time.Duration{10500 * time.Millisecond, time.Second}, 11, 0, false},
+	} {
+		var (
+			count    int
+			action   = Repeat(counted(time.Second, &count), c.times)
+			leftover time.Duration
+			done     bool
+		)
+		for _, step := range c.steps {
+			leftover, done = action.Update(step)
+		}
+		if count != c.count || leftover != c.leftover || done != c.done {
+			t.Errorf("%v: got %v runs, leftover %v, done %v, want %v, %v, %v", c.name, count, leftover, done, c.count, c.leftover, c.done)
+		}
+	}
+}
+
+func TestRepeatZeroDurationForever(t *testing.T) {
+	var (
+		count  int
+		action = Repeat(Callback(func() {
+			count++
+		}), -1)
+	)
+	if leftover, done := action.Update(time.Second); done || leftover != 0 || count != 1 {
+		t.Errorf("Got leftover %v, done %v after %v runs", leftover, done, count)
+	}
+}
+
+func TestPlayerCancel(t *testing.T) {
+	var (
+		player        = NewPlayer()
+		first, second float32
+		a             = player.Start(progress(2*time.Second, &first))
+		b             = player.Start(progress(2*time.Second, &second))
+	)
+	player.Update(time.Second)
+	a.Cancel()
+	player.Update(500 * time.Millisecond)
+	if !a.Done() || first != 0.5 {
+		t.Errorf("Cancelled tween done %v at %v, want true at 0.5", a.Done(), first)
+	}
+	if b.Done() || second != 0.75 || player.Len() != 1 {
+		t.Errorf("Other tween done %v at %v with %v running", b.Done(), second, player.Len())
+	}
+	player.Update(time.Second)
+	if !b.Done() || second != 1 || player.Len() != 0 {
+		t.Errorf("Other tween done %v at %v with %v running", b.Done(), second, player.Len())
+	}
+}
+
+func TestPlayerCancelAllFromCallback(t *testing.T) {
+	var (
+		player  = NewPlayer()
+		started *Running
+		last    float32
+		other   = player.Start(Delay(time.Minute))
+	)
+	player.Start(Callback(func() {
+		player.CancelAll()
+		started = player.Start(progress(time.Second, &last))
+	}))
+	var later = player.Start(Delay(time.Minute))
+	player.Update(time.Second)
+	if !other.Done() || !later.Done() {
+		t.Errorf("CancelAll left actions running")
+	}
+	if started.Done() || last != -1 || player.Len() != 1 {
+		t.Errorf("Action started during the update: done %v, progress %v, %v running", started.Done(), last, player.Len())
+	}
+	player.Update(time.Second)
+	if !started.Done() || last != 1 {
+		t.Errorf("Action started during the update: done %v, progress %v", started.Done(), last)
+	}
+}
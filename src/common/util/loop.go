@@ -0,0 +1,207 @@
+// Copyright 2016 Arne Roomann-Kurrik
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"time"
+)
+
+const (
+	DefaultMaxFrameTime = 250 * time.Millisecond
+	DefaultMaxSteps     = 5
+)
+
+// UpdateFunc advances the simulation by exactly one fixed step.
+type UpdateFunc func(step time.Duration) error
+
+// RenderFunc draws the current state.  Alpha is how far, from 0 to 1, real
+// time has progressed between the last update and the next one, for
+// interpolating between the previous and current simulation state.
+type RenderFunc func(alpha float32) error
+
+// Loop runs updates at a fixed timestep independent of how often frames
+// are rendered.
+type Loop struct {
+	Step           time.Duration // Simulated time per update.
+	RenderInterval time.Duration // Minimum time between renders; 0 renders every frame.
+	MaxFrameTime   time.Duration // Real time consumed per frame is clamped to this.
+	MaxSteps       int           // Updates per frame before the backlog is dropped.
+	scale          float64
+	paused         bool
+	accumulator    time.Duration
+	last           time.Time
+	lastRender     time.Time
+	ticks          uint64
+	dropped        time.Duration
+}
+
+func NewLoop(step time.Duration) *Loop {
+	return &Loop{
+		Step:         step,
+		MaxFrameTime: DefaultMaxFrameTime,
+		MaxSteps:     DefaultMaxSteps,
+		scale:        1.0,
+	}
+}
+
+// NewLoopHz is a convenience for creating a loop which updates rate times
+// per simulated second.
+func NewLoopHz(rate float64) *Loop {
+	return NewLoop(time.Duration(float64(time.Second) / rate))
+}
+
+// SetTimeScale speeds up (> 1) or slows down (< 1) simulated time relative
+// to real time.  The step size is unchanged; only the number of steps per
+// real second varies.
+func (l *Loop) SetTimeScale(scale float64) {
+	if scale < 0 {
+		scale = 0
+	}
+	l.scale = scale
+}
+
+func (l *Loop) TimeScale() float64 {
+	return l.scale
+}
+
+// Pause stops updates.  Rendering continues with a frozen alpha.
+func (l *Loop) Pause() {
+	l.paused = true
+}
+
+func (l *Loop) Resume() {
+	l.paused = false
+}
+
+func (l *Loop) SetPaused(paused bool) {
+	l.paused = paused
+}
+
+func (l *Loop) Paused() bool {
+	return l.paused
+}
+
+// Ticks returns the number of updates run so far.
+func (l *Loop) Ticks() uint64 {
+	return l.ticks
+}
+
+// Dropped returns the total simulated time discarded by spiral-of-death
+// clamping.
+func (l *Loop) Dropped() time.Duration {
+	return l.dropped
+}
+
+func (l *Loop) Alpha() float32 {
+	if l.Step <= 0 {
+		return 1
+	}
+	return float32(float64(l.accumulator) / float64(l.Step))
+}
+
+// Reset forgets accumulated time, so the next Advance starts fresh.  Call
+// after long stalls such as loading.
+func (l *Loop) Reset() {
+	l.accumulator = 0
+	l.last = time.Time{}
+	l.lastRender = time.Time{}
+}
+
+// Advance consumes real time elapsed since the previous call, running as
+// many fixed updates as fit.  It returns the interpolation alpha.
+func (l *Loop) Advance(now time.Time, update UpdateFunc) (alpha float32, err error) {
+	var frame time.Duration
+	if !l.last.IsZero() {
+		frame = now.Sub(l.last)
+	}
+	l.last = now
+	if l.paused || l.Step <= 0 {
+		alpha = l.Alpha()
+		return
+	}
+	if l.MaxFrameTime > 0 && frame > l.MaxFrameTime {
+		l.dropped += time.Duration(float64(frame-l.MaxFrameTime) * l.scale)
+		frame = l.MaxFrameTime
+	}
+	l.accumulator += time.Duration(float64(frame) * l.scale)
+	for steps := 0; l.accumulator >= l.Step; steps++ {
+		if l.MaxSteps > 0 && steps >= l.MaxSteps {
+			// Falling behind; drop whole steps rather than spiral.
+			var backlog = l.accumulator - l.accumulator%l.Step
+			l.dropped += backlog
+			l.accumulator -= backlog
+			break
+		}
+		if err = update(l.Step); err != nil {
+			return
+		}
+		l.accumulator -= l.Step
+		l.ticks++
+	}
+	alpha = l.Alpha()
+	return
+}
+
+// Frame runs one iteration: updates via Advance, then render if at least
+// RenderInterval has passed since the previous render.  Rendered reports
+// whether render was called.
+func (l *Loop) Frame(now time.Time, update UpdateFunc, render RenderFunc) (rendered bool, err error) {
+	var alpha float32
+	if alpha, err = l.Advance(now, update); err != nil {
+		return
+	}
+	if l.RenderInterval > 0 && !l.lastRender.IsZero() && now.Sub(l.lastRender) < l.RenderInterval {
+		return
+	}
+	l.lastRender = now
+	rendered = true
+	err = render(alpha)
+	return
+}
+
+// Run calls Frame until done returns true or a callback fails.  When
+// renders are throttled it sleeps until the next update or render is due
+// instead of spinning.
+func (l *Loop) Run(done func() bool, update UpdateFunc, render RenderFunc) (err error) {
+	var rendered bool
+	l.Reset()
+	for !done() {
+		if rendered, err = l.Frame(time.Now(), update, render); err != nil {
+			return
+		}
+		if !rendered {
+			l.idle()
+		}
+	}
+	return
+}
+
+func (l *Loop) idle() {
+	var wait = l.RenderInterval - time.Since(l.lastRender)
+	if !l.paused && l.scale > 0 && l.Step > 0 {
+		if next := time.Duration(float64(l.Step-l.accumulator) / l.scale); next < wait {
+			wait = next
+		}
+	}
+	if wait > time.Millisecond {
+		time.Sleep(wait)
+	}
+}
+
+// Lerp interpolates between a previous and current value using the alpha
+// passed to a RenderFunc.
+func Lerp(previous, current, alpha float32) float32 {
+	return previous + (current-previous)*alpha
+}
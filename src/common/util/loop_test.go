@@ -0,0 +1,141 @@
+// Copyright 2016 Arne Roomann-Kurrik
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+var (
+	loopStart   = time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	errLoopTest = fmt.Errorf("Update failed")
+)
+
+func at(ms int) time.Time {
+	return loopStart.Add(time.Duration(ms) * time.Millisecond)
+}
+
+// advance feeds each timestamp, in milliseconds, to the loop and returns
+// the alpha from the last.
+func advance(t *testing.T, l *Loop, times ...int) (alpha float32) {
+	t.Helper()
+	var err error
+	for _, ms := range times {
+		if alpha, err = l.Advance(at(ms), func(step time.Duration) error {
+			if step != l.Step {
+				t.Errorf("Got step %v, want %v", step, l.Step)
+			}
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return
+}
+
+func TestLoopAdvance(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		setup   func(l *Loop)
+		times   []int
+		ticks   uint64
+		dropped time.Duration
+		alpha   float32
+	}{
+		{"first call", nil, []int{0}, 0, 0, 0},
+		{"fixed steps", nil, []int{0, 25}, 2, 0, 0.5},
+		{"remainder carried", nil, []int{0, 25, 40}, 4, 0, 0},
+		{"clamped frame", func(l *Loop) {
+			l.MaxFrameTime = 100 * time.Millisecond
+			l.MaxSteps = 0
+		}, []int{0, 1000}, 10, 900 * time.Millisecond, 0},
+		{"steps dropped", func(l *Loop) {
+			l.MaxFrameTime = 0
+			l.MaxSteps = 3
+		}, []int{0, 55}, 3, 20 * time.Millisecond, 0.5},
+		{"clamped then dropped", func(l *Loop) {
+			l.MaxFrameTime = 100 * time.Millisecond
+			l.MaxSteps = 4
+		}, []int{0, 1005}, 4, 965 * time.Millisecond, 0},
+		{"double speed", func(l *Loop) {
+			l.SetTimeScale(2)
+		}, []int{0, 25}, 5, 0, 0},
+		{"half speed", func(l *Loop) {
+			l.SetTimeScale(0.5)
+		}, []int{0, 25}, 1, 0, 0.25},
+		{"stopped", func(l *Loop) {
+			l.SetTimeScale(0)
+		}, []int{0, 25}, 0, 0, 0},
+		{"scaled clamp", func(l *Loop) {
+			l.SetTimeScale(2)
+			l.MaxFrameTime = 100 * time.Millisecond
+			l.MaxSteps = 0
+		}, []int{0, 150}, 20, 100 * time.Millisecond, 0},
+	} {
+		var l = NewLoop(10 * time.Millisecond)
+		if c.setup != nil {
+			c.setup(l)
+		}
+		var alpha = advance(t, l, c.times...)
+		if l.Ticks() != c.ticks || l.Dropped() != c.dropped || alpha != c.alpha {
+			t.Errorf("%v: got %v ticks, %v dropped, alpha %v, want %v, %v, %v",
+				c.name, l.Ticks(), l.Dropped(), alpha, c.ticks, c.dropped, c.alpha)
+		}
+	}
+}
+
+func TestLoopPauseResume(t *testing.T) {
+	var l = NewLoop(10 * time.Millisecond)
+	advance(t, l, 0, 15)
+	l.Pause()
+	if alpha := advance(t, l, 100, 200); l.Ticks() != 1 || alpha != 0.5 {
+		t.Errorf("Paused: got %v ticks, alpha %v, want 1, 0.5", l.Ticks(), alpha)
+	}
+	l.Resume()
+	if alpha := advance(t, l, 210); l.Ticks() != 2 || alpha != 0.5 || l.Dropped() != 0 {
+		t.Errorf("Resumed: got %v ticks, alpha %v, %v dropped, want 2, 0.5, 0", l.Ticks(), alpha, l.Dropped())
+	}
+}
+
+func TestLoopAlphaRange(t *testing.T) {
+	var (
+		l  = NewLoop(16 * time.Millisecond)
+		ms int
+	)
+	advance(t, l, ms)
+	for i := 0; i < 500; i++ {
+		ms += 1 + (i*7)%40
+		if alpha := advance(t, l, ms); alpha < 0 || alpha >= 1 {
+			t.Fatalf("Frame %v: alpha %v outside [0,1)", i, alpha)
+		}
+	}
+}
+
+func TestLoopUpdateError(t *testing.T) {
+	var (
+		l     = NewLoop(10 * time.Millisecond)
+		calls int
+		fail  = func(step time.Duration) error {
+			calls++
+			return errLoopTest
+		}
+	)
+	l.Advance(at(0), fail)
+	if _, err := l.Advance(at(50), fail); err != errLoopTest || calls != 1 {
+		t.Errorf("Got error %v after %v calls, want %v after 1", err, calls, errLoopTest)
+	}
+}
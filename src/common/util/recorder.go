@@ -0,0 +1,298 @@
+// Copyright 2016 Arne Roomann-Kurrik
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/kurrik/opengl-benchmarks/common/core"
+	"image"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+type RecordFormat int
+
+const (
+	RecordPNG RecordFormat = iota // One numbered PNG per frame.
+	RecordGIF                     // A single animated GIF.
+)
+
+var recordFormatNames = map[string]RecordFormat{
+	"png": RecordPNG,
+	"gif": RecordGIF,
+}
+
+func ParseRecordFormat(name string) (format RecordFormat, err error) {
+	var exists bool
+	if format, exists = recordFormatNames[name]; !exists {
+		err = fmt.Errorf("Unknown record format %v", name)
+	}
+	return
+}
+
+const (
+	recorderBuffers = 3
+	recorderQueue   = 16 // Frames waiting to be encoded before more are dropped.
+)
+
+type recorderFrame struct {
+	core.ReadbackFrame
+	timeMs float64
+}
+
+type RecordedFrame struct {
+	Frame  int     `json:"frame"`
+	TimeMs float64 `json:"time_ms"`
+	File   string  `json:"file,omitempty"`
+}
+
+type recordManifest struct {
+	Format  string          `json:"format"`
+	Width   int             `json:"width"`
+	Height  int             `json:"height"`
+	File    string          `json:"file,omitempty"`
+	Dropped int             `json:"dropped"`
+	Frames  []RecordedFrame `json:"frames"`
+}
+
+// Recorder captures a sequence of rendered frames.  Pixels are read back
+// asynchronously and encoded on a separate goroutine, so recording costs
+// little frame time.  When encoding falls behind, frames are dropped rather
+// than stalling rendering.  Close writes a manifest.json listing every
+// recorded frame and when it was rendered.
+type Recorder struct {
+	Dir    string
+	Format RecordFormat
+	Every  int // Capture every Nth frame.
+	// MaxGIFFrames stops a GIF recording after this many frames, since
+	// they are held in memory until Close.  0 means no limit.
+	MaxGIFFrames int
+	context      *core.Context
+	reader       *core.AsyncReader
+	start        time.Time
+	frame        int
+	times        map[int]time.Duration
+	frames       []RecordedFrame
+	dropped      int
+	encode       chan recorderFrame
+	wg           sync.WaitGroup
+	anim         *gif.GIF
+	width        int
+	height       int
+	err          error
+	closed       bool
+	full         bool
+}
+
+func NewRecorder(dir string, format RecordFormat) *Recorder {
+	return &Recorder{
+		Dir:          dir,
+		Format:       format,
+		Every:        1,
+		MaxGIFFrames: 600,
+		times:        map[int]time.Duration{},
+	}
+}
+
+// Attach starts recording every frame swapped by context.
+func (r *Recorder) Attach(context *core.Context) (err error) {
+	if r.context != nil {
+		err = fmt.Errorf("Recorder is already attached")
+		return
+	}
+	if err = os.MkdirAll(r.Dir, 0755); err != nil {
+		return
+	}
+	r.context = context
+	r.reader = core.NewAsyncReader(recorderBuffers)
+	r.encode = make(chan recorderFrame, recorderQueue)
+	r.anim = &gif.GIF{}
+	r.start = time.Now()
+	r.wg.Add(1)
+	go r.run()
+	context.BeforeSwap(r.capture)
+	return
+}
+
+func (r *Recorder) capture() {
+	if r.closed {
+		return
+	}
+	var frame = r.frame
+	r.frame++
+	if r.Every > 1 && frame%r.Every != 0 || r.limited() {
+		r.send(r.reader.Collect(false), false)
+		return
+	}
+	r.width, r.height = r.context.FramebufferSize()
+	r.times[frame] = time.Since(r.start)
+	r.reader.Read(r.width, r.height, frame)
+	r.send(r.reader.Collect(false), false)
+}
+
+// limited reports whether a GIF recording has reached MaxGIFFrames.
+func (r *Recorder) limited() bool {
+	if r.Format != RecordGIF || r.MaxGIFFrames <= 0 {
+		return false
+	}
+	if len(r.frames)+r.reader.Pending() < r.MaxGIFFrames {
+		return false
+	}
+	if !r.full {
+		r.full = true
+		glog.Warningf("Recording reached %v GIF frames, stopping", r.MaxGIFFrames)
+	}
+	return true
+}
+
+// send queues frames for encoding.  Unless wait is set, frames which don't
+// fit in the queue are dropped so that rendering never waits on encoding.
+func (r *Recorder) send(frames []core.ReadbackFrame, wait bool) {
+	for _, f := range frames {
+		var recorded = RecordedFrame{
+			Frame:  f.Tag,
+			TimeMs: r.times[f.Tag].Seconds() * 1000,
+		}
+		if r.Format == RecordPNG {
+			recorded.File = r.frameFile(f.Tag)
+		}
+		delete(r.times, f.Tag)
+		if wait {
+			r.encode <- recorderFrame{f, recorded.TimeMs}
+		} else {
+			select {
+			case r.encode <- recorderFrame{f, recorded.TimeMs}:
+			default:
+				r.dropped++
+				continue
+			}
+		}
+		r.frames = append(r.frames, recorded)
+	}
+}
+
+func (r *Recorder) frameFile(frame int) string {
+	return fmt.Sprintf("frame-%06d.png", frame)
+}
+
+// run encodes frames in the order they were captured.
+func (r *Recorder) run() {
+	defer r.wg.Done()
+	var start, shown float64 // Milliseconds and hundredths of a second.
+	for f := range r.encode {
+		if r.err != nil {
+			continue
+		}
+		core.Opaque(f.Image)
+		switch r.Format {
+		case RecordGIF:
+			var (
+				bounds   = f.Image.Bounds()
+				paletted = image.NewPaletted(bounds, palette.Plan9)
+				count    = len(r.anim.Image)
+			)
+			draw.FloydSteinberg.Draw(paletted, bounds, f.Image, bounds.Min)
+			if count == 0 {
+				start = f.timeMs
+			} else {
+				// GIF delays are in hundredths of a second and say how long
+				// a frame stays up, so the previous frame's delay is known
+				// now.  Rounding the total elapsed time rather than each
+				// delay keeps the animation from drifting.
+				var elapsed = math.Floor((f.timeMs-start)/10 + 0.5)
+				r.anim.Delay[count-1] = int(elapsed - shown)
+				shown = elapsed
+			}
+			r.anim.Image = append(r.anim.Image, paletted)
+			r.anim.Delay = append(r.anim.Delay, 0)
+		default:
+			r.err = core.WritePNG(filepath.Join(r.Dir, r.frameFile(f.Tag)), f.Image)
+		}
+	}
+	if count := len(r.anim.Delay); count > 1 {
+		// The last frame has no successor; show it as long as the one
+		// before.
+		r.anim.Delay[count-1] = r.anim.Delay[count-2]
+	}
+}
+
+// Frames returns the number of frames captured so far.
+func (r *Recorder) Frames() int {
+	return len(r.frames) + r.reader.Pending()
+}
+
+// Dropped returns the number of frames discarded because encoding could
+// not keep up.
+func (r *Recorder) Dropped() int {
+	return r.dropped
+}
+
+// Close finishes outstanding reads and encodes, then writes the GIF (if
+// recording one) and the manifest.
+func (r *Recorder) Close() (err error) {
+	if r.context == nil || r.closed {
+		return
+	}
+	r.send(r.reader.Collect(true), true)
+	r.closed = true
+	close(r.encode)
+	r.wg.Wait()
+	r.reader.Delete()
+	if err = r.err; err != nil {
+		return
+	}
+	var manifest = recordManifest{
+		Format:  "png",
+		Width:   r.width,
+		Height:  r.height,
+		Dropped: r.dropped,
+		Frames:  r.frames,
+	}
+	if r.Format == RecordGIF {
+		manifest.Format = "gif"
+		manifest.File = "recording.gif"
+		if err = r.writeGIF(filepath.Join(r.Dir, manifest.File)); err != nil {
+			return
+		}
+	}
+	var data []byte
+	if data, err = json.MarshalIndent(manifest, "", "  "); err != nil {
+		return
+	}
+	err = ioutil.WriteFile(filepath.Join(r.Dir, "manifest.json"), data, 0644)
+	return
+}
+
+func (r *Recorder) writeGIF(path string) (err error) {
+	var f *os.File
+	if len(r.anim.Image) == 0 {
+		return
+	}
+	if f, err = os.Create(path); err != nil {
+		return
+	}
+	defer f.Close()
+	err = gif.EncodeAll(f, r.anim)
+	return
+}
@@ -0,0 +1,83 @@
+// Copyright 2016 Arne Roomann-Kurrik
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/kurrik/opengl-benchmarks/common/core"
+	"image"
+	"path/filepath"
+	"sync"
+)
+
+// Screenshots saves the rendered frame to PNG files when a key is pressed
+// and, optionally, at a fixed frame interval.  Call Capture once per frame
+// after rendering and before swapping buffers, and Flush before exiting.
+type Screenshots struct {
+	Dir     string
+	Prefix  string
+	Key     core.Key
+	Every   int // Capture every N frames; 0 disables.
+	frame   int
+	count   int
+	writing sync.WaitGroup
+}
+
+func NewScreenshots(dir string) *Screenshots {
+	return &Screenshots{
+		Dir:    dir,
+		Prefix: "screenshot",
+		Key:    core.KeyF12,
+	}
+}
+
+func (s *Screenshots) Capture(context *core.Context) (err error) {
+	var (
+		frame = s.frame
+		img   *image.RGBA
+	)
+	s.frame++
+	if !context.Events.KeyPressed(s.Key) && (s.Every <= 0 || frame%s.Every != 0) {
+		return
+	}
+	if img, err = context.ReadPixels(); err != nil {
+		return
+	}
+	core.Opaque(img)
+	var path = filepath.Join(s.Dir, fmt.Sprintf("%v-%06d.png", s.Prefix, frame))
+	s.count++
+	// Encoding is slow, so keep it off the render thread.
+	s.writing.Add(1)
+	go func() {
+		defer s.writing.Done()
+		if err := core.WritePNG(path, img); err != nil {
+			glog.Errorf("Could not write screenshot %v: %v", path, err)
+			return
+		}
+		glog.Infof("Wrote screenshot %v", path)
+	}()
+	return
+}
+
+// Flush waits until every screenshot taken so far has been written.
+func (s *Screenshots) Flush() {
+	s.writing.Wait()
+}
+
+// Count returns the number of screenshots taken.
+func (s *Screenshots) Count() int {
+	return s.count
+}
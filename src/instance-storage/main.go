@@ -0,0 +1,132 @@
+// Copyright 2016 Arne Roomann-Kurrik
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Compares render.InstanceList with render.InstancePool for the operations
+// the renderer and game code perform most often.  No GL context is needed.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/kurrik/opengl-benchmarks/common/render"
+	"math/rand"
+	"testing"
+)
+
+var countFlag = flag.Int("count", 10000, "Instances per benchmark iteration")
+
+type storage struct {
+	name   string
+	create func() render.Instances
+}
+
+var storages = []storage{
+	storage{
+		name: "InstanceList",
+		create: func() render.Instances {
+			return render.NewInstanceList()
+		},
+	},
+	storage{
+		name: "InstancePool",
+		create: func() render.Instances {
+			return render.NewInstancePool()
+		},
+	},
+}
+
+func populate(s storage, count int) (l render.Instances, all []*render.Instance) {
+	l = s.create()
+	all = make([]*render.Instance, count)
+	for i := 0; i < count; i++ {
+		all[i] = l.NewInstance()
+		all[i].SetPosition(mgl32.Vec3{float32(i), 0, 0})
+	}
+	return
+}
+
+func benchIterate(s storage, count int) func(b *testing.B) {
+	return func(b *testing.B) {
+		var (
+			l, _ = populate(s, count)
+			sum  float32
+		)
+		b.ResetTimer()
+		if indexed, ok := l.(render.IndexedInstances); ok {
+			for n := 0; n < b.N; n++ {
+				for i := 0; i < indexed.Len(); i++ {
+					inst := indexed.At(i)
+					inst.MarkChanged()
+					sum += inst.GetModel()[12]
+				}
+			}
+			return
+		}
+		for n := 0; n < b.N; n++ {
+			for inst := l.Head(); inst != nil; inst = inst.Next() {
+				inst.MarkChanged()
+				sum += inst.GetModel()[12]
+			}
+		}
+	}
+}
+
+func benchInsert(s storage, count int) func(b *testing.B) {
+	return func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			populate(s, count)
+		}
+	}
+}
+
+func benchRemove(s storage, count int) func(b *testing.B) {
+	return func(b *testing.B) {
+		var order = rand.New(rand.NewSource(1)).Perm(count)
+		for n := 0; n < b.N; n++ {
+			b.StopTimer()
+			l, all := populate(s, count)
+			b.StartTimer()
+			for _, i := range order {
+				l.Remove(all[i])
+			}
+		}
+	}
+}
+
+func main() {
+	flag.Parse()
+	var count = *countFlag
+	fmt.Printf("%-14v %-10v %14v %12v %10v\n", "storage", "op", "ns/op", "B/op", "allocs/op")
+	for _, s := range storages {
+		for _, op := range []struct {
+			name  string
+			bench func(storage, int) func(*testing.B)
+		}{
+			{"iterate", benchIterate},
+			{"insert", benchInsert},
+			{"remove", benchRemove},
+		} {
+			var result = testing.Benchmark(op.bench(s, count))
+			fmt.Printf(
+				"%-14v %-10v %14v %12v %10v\n",
+				s.name,
+				op.name,
+				result.NsPerOp(),
+				result.AllocedBytesPerOp(),
+				result.AllocsPerOp(),
+			)
+		}
+	}
+}
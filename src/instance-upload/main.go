@@ -0,0 +1,155 @@
+// Copyright 2016 Arne Roomann-Kurrik
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Renders the same sprites with each instance upload strategy and reports
+// the time per frame alongside the time spent in Render, which is where
+// instance data is streamed.  Vsync is disabled so frames are not capped
+// by the display.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/kurrik/opengl-benchmarks/common/core"
+	"github.com/kurrik/opengl-benchmarks/common/loaders"
+	"github.com/kurrik/opengl-benchmarks/common/render"
+	"github.com/kurrik/opengl-benchmarks/common/sprites"
+	"runtime"
+	"testing"
+	"time"
+)
+
+var (
+	spritesFlag       = flag.Int("sprites", 10000, "Sprite instances drawn each frame")
+	batchFlag         = flag.Int("batch", 1000, "Maximum instances uploaded per draw call")
+	uploadBuffersFlag = flag.Int("upload-buffers", 8, "Buffer count or ring segments for rotating upload strategies")
+)
+
+func init() {
+	runtime.LockOSThread()
+}
+
+func benchFrames(
+	context *core.Context,
+	renderer *render.Renderer,
+	camera *core.Camera,
+	sheet *sprites.Sheet,
+	geometry *render.Geometry,
+	instances render.Instances,
+	rendering *time.Duration,
+) func(b *testing.B) {
+	return func(b *testing.B) {
+		*rendering = 0
+		for n := 0; n < b.N; n++ {
+			context.Clear()
+			renderer.Bind()
+			sheet.Bind()
+			var start = time.Now()
+			if err := renderer.Render(camera, sheet, geometry, instances); err != nil {
+				b.Fatal(err)
+			}
+			*rendering += time.Since(start)
+			renderer.Unbind()
+			context.SwapBuffers()
+		}
+	}
+}
+
+func main() {
+	flag.Parse()
+	var (
+		context   *core.Context
+		camera    *core.Camera
+		sheet     *sprites.Sheet
+		renderer  *render.Renderer
+		instances *sprites.SpriteInstanceList
+		square    *render.Geometry
+		inst      *render.Instance
+		rendering time.Duration
+		err       error
+	)
+	if context, err = core.NewContext(); err != nil {
+		panic(err)
+	}
+	if err = context.CreateWindow(640, 480, "instance-upload"); err != nil {
+		panic(err)
+	}
+	defer context.Delete()
+	context.SetSwapInterval(0)
+	if camera, err = context.Camera(mgl32.Vec3{0, 0, 0}, mgl32.Vec3{6.4, 4.8, 2}); err != nil {
+		panic(err)
+	}
+	if sheet, err = loaders.NewTexturePackerLoader().Load(
+		"src/resources/spritesheet.json",
+		core.TextureOptions{Smoothing: core.SmoothingNearest},
+	); err != nil {
+		panic(err)
+	}
+	if renderer, err = render.NewRenderer(*batchFlag); err != nil {
+		panic(err)
+	}
+	renderer.SetBlendState(context.Blend)
+	square = render.NewGeometryFromIndexedPoints(render.SquareIndexed, render.QuadIndices)
+	instances = sprites.NewSpriteInstanceList(sheet, 100)
+	for i := 0; i < *spritesFlag; i++ {
+		inst = instances.NewInstance()
+		if err = instances.SetFrame(inst, "numbered_squares_01"); err != nil {
+			panic(err)
+		}
+		inst.SetPosition(mgl32.Vec3{
+			float32(i%64)/10.0 - 3.2,
+			float32(i/64%48)/10.0 - 2.4,
+			0,
+		})
+	}
+	fmt.Printf(
+		"%v sprites, batch %v, %v buffers\n",
+		*spritesFlag,
+		*batchFlag,
+		*uploadBuffersFlag,
+	)
+	fmt.Printf("%-12v %10v %14v %14v\n", "strategy", "frames", "ns/frame", "render ns")
+	for _, c := range []struct {
+		name     string
+		strategy core.UploadStrategy
+	}{
+		{"subdata", core.UploadSubData},
+		{"orphan", core.UploadOrphan},
+		{"roundrobin", core.UploadRoundRobin},
+		{"map", core.UploadMapInvalidate},
+		{"ring", core.UploadRing},
+	} {
+		renderer.SetUploadStrategy(c.strategy, *uploadBuffersFlag)
+		var result = testing.Benchmark(benchFrames(
+			context,
+			renderer,
+			camera,
+			sheet,
+			square,
+			instances,
+			&rendering,
+		))
+		fmt.Printf(
+			"%-12v %10v %14v %14v\n",
+			c.name,
+			result.N,
+			result.NsPerOp(),
+			rendering.Nanoseconds()/int64(result.N),
+		)
+	}
+	renderer.Delete()
+	square.Delete()
+	sheet.Delete()
+}
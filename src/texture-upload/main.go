@@ -0,0 +1,141 @@
+// Copyright 2016 Arne Roomann-Kurrik
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Measures how long it takes to turn images of various types into
+// textures.  RGBA images uploaded premultiplied and NRGBA images uploaded
+// with straight alpha take the zero-copy path; others are converted first.
+// "legacy" repeats the per-byte conversion which used to precede every
+// upload, for comparison.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"github.com/kurrik/opengl-benchmarks/common/core"
+	"image"
+	"image/color"
+	"image/draw"
+	"runtime"
+	"testing"
+)
+
+var sizeFlag = flag.Int("size", 512, "Width and height of the uploaded images")
+
+func init() {
+	runtime.LockOSThread()
+}
+
+func fill(img draw.Image) draw.Image {
+	var bounds = img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			img.Set(x, y, color.NRGBA{uint8(x), uint8(y), uint8(x ^ y), 200})
+		}
+	}
+	return img
+}
+
+// legacyBytes is the conversion previously done by core before uploading
+// with UNSIGNED_INT_8_8_8_8.
+func legacyBytes(img image.Image) *bytes.Buffer {
+	var (
+		bounds = img.Bounds()
+		rgba   = image.NewRGBA(bounds)
+		data   = make([]byte, len(rgba.Pix))
+		buf    = &bytes.Buffer{}
+	)
+	draw.Draw(rgba, bounds, img, bounds.Min, draw.Src)
+	for src, dest := 0, len(data)-rgba.Stride; src < len(rgba.Pix); src, dest = src+rgba.Stride, dest-rgba.Stride {
+		copy(data[dest:dest+rgba.Stride], rgba.Pix[src:src+rgba.Stride])
+	}
+	for x := 0; x < len(data); x += 4 {
+		buf.WriteByte(data[x+3])
+		buf.WriteByte(data[x+2])
+		buf.WriteByte(data[x+1])
+		buf.WriteByte(data[x+0])
+	}
+	return buf
+}
+
+func benchUpload(img image.Image, opts core.TextureOptions) func(b *testing.B) {
+	return func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			texture, err := core.GetTexture(img, opts)
+			if err != nil {
+				b.Fatal(err)
+			}
+			texture.Delete()
+		}
+	}
+}
+
+func benchLegacy(img image.Image, opts core.TextureOptions) func(b *testing.B) {
+	return func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			legacyBytes(img)
+		}
+	}
+}
+
+func main() {
+	flag.Parse()
+	var (
+		context  *core.Context
+		err      error
+		size     = *sizeFlag
+		bounds   = image.Rect(0, 0, size, size)
+		rgba     = fill(image.NewRGBA(bounds))
+		nrgba    = fill(image.NewNRGBA(bounds))
+		sub      = rgba.(*image.RGBA).SubImage(image.Rect(size/4, size/4, size*3/4, size*3/4))
+		gray     = fill(image.NewGray(bounds))
+		straight = core.TextureOptions{Smoothing: core.SmoothingLinear}
+		premult  = core.TextureOptions{Smoothing: core.SmoothingLinear, Premultiply: true}
+	)
+	if context, err = core.NewContext(); err != nil {
+		panic(err)
+	}
+	if err = context.CreateWindow(64, 64, "texture-upload"); err != nil {
+		panic(err)
+	}
+	defer context.Delete()
+	fmt.Printf("%-10v %-8v %-9v %14v %12v %10v\n", "image", "path", "alpha", "ns/op", "B/op", "allocs/op")
+	for _, c := range []struct {
+		name  string
+		img   image.Image
+		path  string
+		alpha string
+		opts  core.TextureOptions
+		bench func(image.Image, core.TextureOptions) func(*testing.B)
+	}{
+		{"RGBA", rgba, "upload", "premult", premult, benchUpload},
+		{"NRGBA", nrgba, "upload", "straight", straight, benchUpload},
+		{"RGBA sub", sub, "upload", "premult", premult, benchUpload},
+		{"RGBA", rgba, "upload", "straight", straight, benchUpload},
+		{"NRGBA", nrgba, "upload", "premult", premult, benchUpload},
+		{"Gray", gray, "upload", "straight", straight, benchUpload},
+		{"RGBA", rgba, "legacy", "", straight, benchLegacy},
+	} {
+		var result = testing.Benchmark(c.bench(c.img, c.opts))
+		fmt.Printf(
+			"%-10v %-8v %-9v %14v %12v %10v\n",
+			c.name,
+			c.path,
+			c.alpha,
+			result.NsPerOp(),
+			result.AllocedBytesPerOp(),
+			result.AllocsPerOp(),
+		)
+	}
+}
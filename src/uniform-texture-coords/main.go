@@ -21,12 +21,16 @@ import (
 	"github.com/golang/glog"
 	"github.com/kurrik/opengl-benchmarks/common/core"
 	"github.com/kurrik/opengl-benchmarks/common/loaders"
+	"github.com/kurrik/opengl-benchmarks/common/postfx"
 	"github.com/kurrik/opengl-benchmarks/common/render"
 	"github.com/kurrik/opengl-benchmarks/common/sprites"
 	"github.com/kurrik/opengl-benchmarks/common/text"
+	"github.com/kurrik/opengl-benchmarks/common/tween"
 	"github.com/kurrik/opengl-benchmarks/common/util"
 	"image/color"
 	"runtime"
+	"strings"
+	"time"
 )
 
 const BATCH = `
@@ -41,11 +45,55 @@ type Inst struct {
 	R   float32
 }
 
+var (
+	uploadFlag        = flag.String("upload", "subdata", "Instance upload strategy: subdata, orphan, roundrobin, map or ring")
+	uploadBuffersFlag = flag.Int("upload-buffers", 8, "Buffer count or ring segments for rotating upload strategies")
+	batchFlag         = flag.Int("batch", 100, "Maximum instances uploaded per draw call")
+	spritesFlag       = flag.Int("sprites", 0, "Additional sprite instances to render")
+	workersFlag       = flag.Int("workers", 1, "Goroutines used to pack instance data for each render call")
+	retainedFlag      = flag.Bool("retained", false, "Keep instance data on the GPU and upload only changes")
+	updateRateFlag    = flag.Float64("update-rate", 60, "Fixed simulation updates per second")
+	renderRateFlag    = flag.Float64("render-rate", 0, "Maximum renders per second, 0 for every frame")
+	timeScaleFlag     = flag.Float64("time-scale", 1, "Simulated seconds per real second")
+	screenshotDirFlag = flag.String("screenshot-dir", ".", "Directory for screenshots taken with F12")
+	screenshotsFlag   = flag.Int("screenshot-every", 0, "Also take a screenshot every N frames, 0 to disable")
+	recordFlag        = flag.String("record", "", "Directory to record frames into, empty to disable")
+	recordFormatFlag  = flag.String("record-format", "png", "Recording format: png or gif")
+	recordEveryFlag   = flag.Int("record-every", 1, "Record every Nth frame")
+	premultiplyFlag   = flag.Bool("premultiply", false, "Premultiply sprite sheet alpha at load")
+	postfxFlag        = flag.String("postfx", "", "Comma separated effects: grade, bloom, blur, vignette, crt, pixelate")
+)
+
 func init() {
 	// See https://code.google.com/p/go/issues/detail?id=3527
 	runtime.LockOSThread()
 }
 
+func newEffect(name string) (effect postfx.Effect, err error) {
+	switch name {
+	case "grade":
+		var pass *postfx.Pass
+		if pass, err = postfx.NewColorGrade(); err == nil {
+			pass.SetFloat("u_Contrast", 1.2)
+			pass.SetFloat("u_Saturation", 1.3)
+		}
+		effect = pass
+	case "bloom":
+		effect, err = postfx.NewBloom(0.6, 1.0)
+	case "blur":
+		effect, err = postfx.NewBlur(1.0)
+	case "vignette":
+		effect, err = postfx.NewVignette()
+	case "crt":
+		effect, err = postfx.NewCRT()
+	case "pixelate":
+		effect, err = postfx.NewPixelate(4)
+	default:
+		err = fmt.Errorf("Unknown effect %v", name)
+	}
+	return
+}
+
 func main() {
 	flag.Parse()
 
@@ -74,6 +122,18 @@ func main() {
 		textInstances   *text.TextInstanceList
 		batchInstances  *render.InstanceList
 		square          *render.Geometry
+		strategy        core.UploadStrategy
+		animator        *sprites.Animator
+		animations      map[string]*sprites.Animation
+		animated        *render.Instance
+		tweens          = tween.NewPlayer()
+		loop            = util.NewLoopHz(*updateRateFlag)
+		screenshots     = util.NewScreenshots(*screenshotDirFlag)
+		recorder        *util.Recorder
+		recordFormat    util.RecordFormat
+		pipeline        *postfx.Pipeline
+		effect          postfx.Effect
+		start           = time.Now()
 	)
 	if context, err = core.NewContext(); err != nil {
 		panic(err)
@@ -81,13 +141,63 @@ func main() {
 	if err = context.CreateWindow(WinWidth, WinHeight, WinTitle); err != nil {
 		panic(err)
 	}
-	if renderer, err = render.NewRenderer(100); err != nil {
+	if renderer, err = render.NewRenderer(*batchFlag); err != nil {
+		panic(err)
+	}
+	renderer.SetBlendState(context.Blend)
+	if strategy, err = core.ParseUploadStrategy(*uploadFlag); err != nil {
 		panic(err)
 	}
+	renderer.SetUploadStrategy(strategy, *uploadBuffersFlag)
+	renderer.SetWorkers(*workersFlag)
+	loop.SetTimeScale(*timeScaleFlag)
+	screenshots.Every = *screenshotsFlag
+	if *postfxFlag != "" {
+		var w, h = context.FramebufferSize()
+		if pipeline, err = postfx.NewPipeline(w, h, core.ColorRGBA8); err != nil {
+			panic(err)
+		}
+		pipeline.SetBlendState(context.Blend)
+		for _, name := range strings.Split(*postfxFlag, ",") {
+			if effect, err = newEffect(strings.TrimSpace(name)); err != nil {
+				panic(err)
+			}
+			pipeline.Add(effect)
+		}
+	}
+	if *recordFlag != "" {
+		if recordFormat, err = util.ParseRecordFormat(*recordFormatFlag); err != nil {
+			panic(err)
+		}
+		recorder = util.NewRecorder(*recordFlag, recordFormat)
+		recorder.Every = *recordEveryFlag
+		if err = recorder.Attach(context); err != nil {
+			panic(err)
+		}
+	}
+	if *renderRateFlag > 0 {
+		loop.RenderInterval = time.Duration(float64(time.Second) / *renderRateFlag)
+	}
+	glog.Infof(
+		"Upload strategy %v, %v buffers, batch %v, %v workers",
+		*uploadFlag,
+		*uploadBuffersFlag,
+		*batchFlag,
+		*workersFlag,
+	)
+	glog.Infof(
+		"Update rate %v/s, render rate %v/s, time scale %v",
+		*updateRateFlag,
+		*renderRateFlag,
+		*timeScaleFlag,
+	)
 
 	if sheet, err = loaders.NewTexturePackerLoader().Load(
 		"src/resources/spritesheet.json",
-		core.SmoothingNearest,
+		core.TextureOptions{
+			Smoothing:   core.SmoothingNearest,
+			Premultiply: *premultiplyFlag,
+		},
 	); err != nil {
 		panic(err)
 	}
@@ -112,7 +222,7 @@ func main() {
 
 	batchInstances.NewInstance()
 
-	square = render.NewGeometryFromPoints(render.Square)
+	square = render.NewGeometryFromIndexedPoints(render.SquareIndexed, render.QuadIndices)
 
 	textInstances = text.NewTextInstanceList(text.Config{
 		TextureWidth:  512,
@@ -139,6 +249,24 @@ func main() {
 		inst.SetPosition(mgl32.Vec3{s.X, s.Y, 0})
 		inst.SetRotation(s.R)
 	}
+	for i := 0; i < *spritesFlag; i++ {
+		inst = spriteInstances.NewInstance()
+		if err = spriteInstances.SetFrame(inst, "numbered_squares_01"); err != nil {
+			panic(err)
+		}
+		inst.SetPosition(mgl32.Vec3{
+			float32(i%64)/10.0 - 3.2,
+			float32(i/64%48)/10.0 - 2.4,
+			0,
+		})
+	}
+	animator = sprites.NewAnimator(spriteInstances)
+	animations = sprites.AnimationsFromSheet(sheet, 100*time.Millisecond, sprites.AnimationPingPong)
+	animated = spriteInstances.NewInstance()
+	if err = animator.Play(animated, animations["numbered_squares"], nil); err != nil {
+		panic(err)
+	}
+	animated.SetPosition(mgl32.Vec3{2.0, -1.5, 0})
 	for _, s := range []Inst{
 		Inst{Key: "numbered_squares_01", X: 0, Y: 0, R: 0},
 		Inst{Key: "numbered_squares_02", X: -1.5, Y: -1.5, R: -15},
@@ -152,21 +280,47 @@ func main() {
 		inst.SetRotation(s.R)
 	}
 
+	tweens.Start(tween.Repeat(
+		tween.Rotation(inst, inst.Rotation()+360, 6*time.Second, tween.Linear),
+		-1,
+	))
+	tweens.Start(tween.Repeat(tween.Sequence(
+		tween.Opacity(animated, 0.2, time.Second, tween.SineInOut),
+		tween.Opacity(animated, 1.0, time.Second, tween.SineInOut),
+	), -1))
+
 	// fmt.Printf("Sheet: %v\n", sprites.Tiles)
 	// fmt.Printf("BatchData: %v\n", batchData)
 	// fmt.Printf("Framerate: %v\n", framerate)
 	// fmt.Printf("TextLoader: %v\n", textLoader)
 	// fmt.Printf("Font: %v\n", font)
 
-	for !context.ShouldClose() {
+	err = loop.Run(func() bool {
 		context.Events.Poll()
+		return context.ShouldClose()
+	}, func(step time.Duration) (err error) {
+		if err = animator.Update(step); err != nil {
+			return
+		}
+		tweens.Update(step)
+		return
+	}, func(alpha float32) (err error) {
 		context.Clear()
+		if pipeline != nil {
+			pipeline.Time = float32(time.Since(start).Seconds())
+			pipeline.Begin()
+		}
 
 		renderer.Bind()
 		sheet.Bind()
 
-		renderer.Render(camera, sheet, batchData, batchInstances)
-		renderer.Render(camera, sheet, square, spriteInstances)
+		if *retainedFlag {
+			renderer.RenderRetained(camera, sheet, batchData, batchInstances)
+			renderer.RenderRetained(camera, sheet, square, spriteInstances)
+		} else {
+			renderer.Render(camera, sheet, batchData, batchInstances)
+			renderer.Render(camera, sheet, square, spriteInstances)
+		}
 
 		textInstances.Bind()
 		renderer.Render(camera, textInstances.Sheet(), square, textInstances)
@@ -174,10 +328,19 @@ func main() {
 
 		renderer.Unbind()
 
+		if pipeline != nil {
+			if err = pipeline.End(); err != nil {
+				return
+			}
+		}
+
 		framerate.Bind()
 		framerate.Render(camera)
 		framerate.Unbind()
 
+		if err = screenshots.Capture(context); err != nil {
+			return
+		}
 		context.SwapBuffers()
 
 		if err = textInstances.SetText(
@@ -185,15 +348,28 @@ func main() {
 			fmt.Sprintf("Rotation %v", rot%100),
 			font,
 		); err != nil {
-			fmt.Printf("ERROR: %v\n", err)
-			break
+			return
 		}
-		inst.SetRotation(float32(rot))
 		rot += 1
+		return
+	})
+	if err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+	}
+	glog.Infof("Ran %v updates, dropped %v", loop.Ticks(), loop.Dropped())
+	screenshots.Flush()
+	if recorder != nil {
+		if err = recorder.Close(); err != nil {
+			panic(err)
+		}
+		glog.Infof("Recorded %v frames to %v, dropped %v", recorder.Frames(), *recordFlag, recorder.Dropped())
 	}
 	if err = core.WritePNG("test-packed.png", textInstances.Sheet().Image()); err != nil {
 		panic(err)
 	}
 	textInstances.Delete()
+	if pipeline != nil {
+		pipeline.Delete()
+	}
 	glog.Flush()
 }